@@ -0,0 +1,90 @@
+package elogrus
+
+import (
+	"sync"
+	"time"
+)
+
+// latencyWeight is the weight of the latest request
+// in the moving average of the bulk request latency
+const latencyWeight = 0.3
+
+// WithAdaptiveBatchSize makes a bulk hook tune its batch size between
+// min and max. The size grows while bulk requests complete well within
+// the target latency and shrinks if they are slower or fail.
+func WithAdaptiveBatchSize(min, max int, target time.Duration) Option {
+	return func(hook *ElasticHook) {
+		if min < 1 || max < min {
+			return
+		}
+		hook.sizer = &batchSizer{
+			min:    min,
+			max:    max,
+			size:   min,
+			target: target,
+		}
+	}
+}
+
+// batchSizer adapts the batch size to the observed latency
+type batchSizer struct {
+	mu       sync.Mutex
+	min      int
+	max      int
+	size     int
+	target   time.Duration
+	averaged time.Duration
+}
+
+// current returns the batch size to use
+func (b *batchSizer) current() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// observe adjusts the batch size to the outcome of a bulk request
+func (b *batchSizer) observe(latency time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.averaged == 0 {
+		b.averaged = latency
+	} else {
+		b.averaged = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(b.averaged))
+	}
+
+	switch {
+	case failed:
+		b.size /= 2
+	case b.averaged > b.target:
+		b.size = b.size * 3 / 4
+	case b.averaged < b.target/2:
+		b.size += b.size/4 + 1
+	}
+	if b.size < b.min {
+		b.size = b.min
+	}
+	if b.size > b.max {
+		b.size = b.max
+	}
+}
+
+// batchLimit returns the number of documents sent in a single bulk request
+func (hook *ElasticHook) batchLimit() int {
+	if hook.sizer != nil {
+		return hook.sizer.current()
+	}
+	return hook.batchSize
+}
+
+// observeBulk passes the outcome of a bulk request
+// to the batch sizer and the concurrency limiter
+func (hook *ElasticHook) observeBulk(latency time.Duration, failed bool) {
+	if hook.sizer != nil {
+		hook.sizer.observe(latency, failed)
+	}
+	if hook.limiter != nil {
+		hook.limiter.observe(failed)
+	}
+}
@@ -0,0 +1,31 @@
+package elogrus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchSizerGrows(t *testing.T) {
+	b := &batchSizer{min: 10, max: 100, size: 10, target: time.Second}
+	for i := 0; i < 50; i++ {
+		b.observe(10*time.Millisecond, false)
+	}
+	if b.current() != 100 {
+		t.Errorf("Expected batch size to grow to 100 got %d", b.current())
+	}
+}
+
+func TestBatchSizerShrinks(t *testing.T) {
+	b := &batchSizer{min: 10, max: 100, size: 100, target: time.Second}
+	b.observe(10*time.Millisecond, true)
+	if b.current() != 50 {
+		t.Errorf("Expected failure to halve batch size got %d", b.current())
+	}
+
+	for i := 0; i < 50; i++ {
+		b.observe(5*time.Second, false)
+	}
+	if b.current() != 10 {
+		t.Errorf("Expected batch size to shrink to 10 got %d", b.current())
+	}
+}
@@ -0,0 +1,147 @@
+package elogrus
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerPollInterval is the time waiting senders sleep
+// while another request probes a half open circuit
+const breakerPollInterval = 100 * time.Millisecond
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// breaker stops requests to ElasticSearch after a number of consecutive
+// failures. Once the cooldown elapsed a single probe request is let
+// through which either closes the circuit again or reopens it.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     circuitState
+	openedAt  time.Time
+	probing   bool
+}
+
+// WithCircuitBreaker stops sending requests after threshold consecutive
+// failures. While the circuit is open, synchronous hooks fail with
+// ErrCircuitOpen and asynchronous hooks keep entries in their queue.
+// After cooldown a probe request decides whether to resume.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(hook *ElasticHook) {
+		if threshold > 0 {
+			hook.breaker = &breaker{
+				threshold: threshold,
+				cooldown:  cooldown,
+			}
+		}
+	}
+}
+
+// acquire returns zero if a request may be sent or
+// the time to wait before asking again otherwise
+func (b *breaker) acquire() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if wait := b.cooldown - time.Since(b.openedAt); wait > 0 {
+			return wait
+		}
+		b.state = circuitHalfOpen
+		b.probing = true
+		return 0
+	case circuitHalfOpen:
+		if b.probing {
+			return breakerPollInterval
+		}
+		b.probing = true
+		return 0
+	}
+	return 0
+}
+
+// record updates the circuit with the outcome of a request
+// and reports whether the circuit was opened or closed
+func (b *breaker) record(success bool) (circuitState, bool) {
+	if b == nil {
+		return circuitClosed, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		changed := b.state != circuitClosed
+		b.failures = 0
+		b.state = circuitClosed
+		return b.state, changed
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+		return b.state, true
+	}
+	return b.state, false
+}
+
+// release lets another request probe the circuit
+// without recording the outcome of the request
+func (b *breaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// current returns the state of the circuit
+func (b *breaker) current() circuitState {
+	if b == nil {
+		return circuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// acquireCircuit waits until the circuit breaker lets a request pass.
+// Synchronous hooks do not wait but fail with ErrCircuitOpen.
+func (hook *ElasticHook) acquireCircuit() error {
+	for {
+		wait := hook.breaker.acquire()
+		if wait == 0 {
+			return nil
+		}
+		if hook.mode == modeSync {
+			return ErrCircuitOpen
+		}
+		if !hook.wait(wait) {
+			return hook.ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+)
+
+func TestBreakerOpens(t *testing.T) {
+	b := &breaker{threshold: 2, cooldown: time.Hour}
+
+	b.record(false)
+	if wait := b.acquire(); wait != 0 {
+		t.Fatal("Circuit opened too early")
+	}
+	b.record(false)
+	if wait := b.acquire(); wait == 0 {
+		t.Fatal("Circuit should be open")
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: 10 * time.Millisecond}
+	b.record(false)
+
+	time.Sleep(20 * time.Millisecond)
+	if wait := b.acquire(); wait != 0 {
+		t.Fatal("Probe request not allowed after cooldown")
+	}
+	if wait := b.acquire(); wait == 0 {
+		t.Fatal("Only a single probe request is allowed")
+	}
+
+	b.record(false)
+	if wait := b.acquire(); wait == 0 {
+		t.Fatal("Failed probe should reopen the circuit")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	b.acquire()
+	b.record(true)
+	if wait := b.acquire(); wait != 0 {
+		t.Fatal("Successful probe should close the circuit")
+	}
+}
+
+func TestBreakerIgnoresCancelled(t *testing.T) {
+	server, client := newStubElastic(nil)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	hook := &ElasticHook{
+		client:      client,
+		ctx:         ctx,
+		ctxCancel:   cancel,
+		initialized: 1,
+		breaker:     &breaker{threshold: 2, cooldown: time.Hour},
+	}
+	hook.breaker.record(false)
+
+	cancel()
+	if _, err := hook.perform(elastic.PerformRequestOptions{Method: "POST", Path: "/log/log", Body: "{}"}); err == nil {
+		t.Fatal("Expected the cancelled request to fail")
+	}
+	if hook.breaker.failures != 1 || hook.breaker.probing {
+		t.Errorf("Expected the cancelled request not to be recorded got %d failures", hook.breaker.failures)
+	}
+}
@@ -0,0 +1,402 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultBatchSize = 100
+
+// bulkActionOverhead is the size of the action line
+// preceding each document in a bulk request, without the index name
+var bulkActionOverhead = len(`{"index":{"_index":"","_type":"log"}}`) + 2
+
+// BulkItemError describes a document of a
+// bulk request which was rejected by ElasticSearch
+type BulkItemError struct {
+	Index  string
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *BulkItemError) Error() string {
+	return fmt.Sprintf("Document rejected by index %s with status %d: %s: %s", e.Index, e.Status, e.Type, e.Reason)
+}
+
+// size returns the number of bytes the document
+// occupies in a bulk request body
+func (doc *document) size() int {
+	return len(doc.body) + len(doc.index) + bulkActionOverhead
+}
+
+// NewBulkElasticHook creates new hook which collects entries and
+// sends them to ElasticSearch in batches using the bulk API.
+// client - ElasticSearch client using gopkg.in/olivere/elastic.v5
+// host - host of system
+// level - log level
+// index - name of the index in ElasticSearch
+func NewBulkElasticHook(client *elastic.Client, host string, level logrus.Level, index string, opts ...Option) (*ElasticHook, error) {
+	return NewBulkElasticHookWithFunc(client, host, level, func() string { return index }, opts...)
+}
+
+// NewBulkElasticHookWithFunc creates new bulk hook with
+// function that provides the index name. This is useful if the index name is
+// somehow dynamic especially based on time.
+// client - ElasticSearch client using gopkg.in/olivere/elastic.v5
+// host - host of system
+// level - log level
+// indexFunc - function providing the name of index
+func NewBulkElasticHookWithFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc, opts ...Option) (*ElasticHook, error) {
+	return newHookFuncAndFireFunc(client, host, level, indexFunc, asyncFireFunc, append([]Option{bulkMode}, opts...)...)
+}
+
+// WithBatchSize sets the number of entries sent
+// in a single bulk request
+func WithBatchSize(size int) Option {
+	return func(hook *ElasticHook) {
+		if size > 0 {
+			hook.batchSize = size
+		}
+	}
+}
+
+// WithBatchBytes sets the maximum size in bytes of a single bulk
+// request body. A batch is sent early as soon as adding another
+// document would exceed the limit. A zero value disables the limit.
+func WithBatchBytes(size int) Option {
+	return func(hook *ElasticHook) {
+		hook.batchBytes = size
+	}
+}
+
+// WithFlushInterval sets the interval after which a partially
+// filled batch is sent. A zero interval disables timed flushes.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(hook *ElasticHook) {
+		hook.flushInterval = interval
+	}
+}
+
+func bulkMode(hook *ElasticHook) {
+	hook.mode = modeBulk
+}
+
+// FireBatch sends the entries in bulk requests right away, regardless
+// of the kind of hook, and waits until they are delivered. The requests
+// are split according to the batch size and WithBatchBytes. Entries of
+// levels not handled by the hook are skipped. If entries could not be
+// delivered, a *BulkError is returned.
+func (hook *ElasticHook) FireBatch(entries []*logrus.Entry) error {
+	hook.closeMu.RLock()
+	defer hook.closeMu.RUnlock()
+	if hook.closed {
+		return ErrHookClosed
+	}
+
+	batchErr := &BulkError{}
+	fail := func(err error) {
+		if err != nil {
+			batchErr.Errors = append(batchErr.Errors, err)
+		}
+	}
+
+	index := hook.index()
+	docs := make([]*document, 0, len(entries))
+	for _, entry := range entries {
+		if !hook.handles(entry.Level) {
+			continue
+		}
+		doc, err := hook.newDocument(entry, index)
+		if err == ErrSkipEntry {
+			continue
+		}
+		if err != nil {
+			fail(err)
+			continue
+		}
+		doc.done = fail
+		docs = append(docs, doc)
+	}
+
+	batchErr.Total = len(docs) + len(batchErr.Errors)
+	atomic.AddInt64(&hook.enqueued, int64(len(docs)))
+	for len(docs) > 0 {
+		n := hook.batchEnd(docs)
+		hook.sendBulk(docs[:n])
+		docs = docs[n:]
+	}
+
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// batchEnd returns the number of documents sent in the next bulk
+// request, limited by the batch size and the size of the request body.
+// A single document exceeding the size limit is sent by itself.
+func (hook *ElasticHook) batchEnd(docs []*document) int {
+	limit := hook.batchLimit()
+	n, size := 0, 0
+	for n < len(docs) && n < limit {
+		size += docs[n].size()
+		if n > 0 && hook.batchBytes > 0 && size > hook.batchBytes {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// handles reports whether entries of the level are sent by the hook
+func (hook *ElasticHook) handles(level logrus.Level) bool {
+	for _, l := range hook.levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// runBulk collects documents and sends them as soon as a batch
+// is complete, is large enough or the flush interval elapsed
+func (hook *ElasticHook) runBulk() {
+	defer hook.wg.Done()
+	if !hook.awaitReady() {
+		return
+	}
+
+	var tick <-chan time.Time
+	if hook.flushInterval > 0 {
+		ticker := time.NewTicker(hook.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batch := make([]*document, 0, hook.batchSize)
+	batchBytes := 0
+	flush := func() {
+		if len(batch) > 0 {
+			hook.dispatchBulk(batch)
+			batch = make([]*document, 0, hook.batchLimit())
+			batchBytes = 0
+		}
+	}
+
+	add := func(doc *document) {
+		size := doc.size()
+		if hook.batchBytes > 0 && batchBytes+size > hook.batchBytes {
+			flush()
+		}
+		batch = append(batch, doc)
+		batchBytes += size
+		if len(batch) >= hook.batchLimit() || (hook.batchBytes > 0 && batchBytes >= hook.batchBytes) {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case <-hook.queue.ready:
+			if doc, ok := hook.queue.tryPop(); ok {
+				add(doc)
+			}
+		case <-tick:
+			flush()
+		case <-hook.flushNow:
+			for doc, ok := hook.queue.tryPop(); ok; doc, ok = hook.queue.tryPop() {
+				add(doc)
+			}
+			flush()
+		case <-hook.closing:
+			for doc, ok := hook.queue.tryPop(); ok; doc, ok = hook.queue.tryPop() {
+				add(doc)
+			}
+			flush()
+			return
+		case <-hook.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendBulk sends the documents using the bulk API. Failed requests and
+// documents rejected with a temporary error are sent again according
+// to the retry policy, the other failures are passed to the error handler.
+// Requests rejected as too large are split in halves which are sent
+// separately.
+func (hook *ElasticHook) sendBulk(docs []*document) {
+	for attempt := 1; ; attempt++ {
+		canRetry := attempt < hook.retry.MaxAttempts
+
+		operation := "elogrus.bulk"
+		if attempt > 1 {
+			operation = "elogrus.retry"
+		}
+		end := hook.startSpan(hook.ctx, operation, map[string]interface{}{
+			"index":   docs[0].index,
+			"entries": len(docs),
+			"attempt": attempt,
+		})
+		start := time.Now()
+		ret, res, err := hook.bulkRequest(docs)
+		end(err)
+		if err != nil {
+			// Requests too large are no sign of an overloaded cluster, so
+			// they are not passed to the batch sizer and the limiter
+			if len(docs) > 1 && elastic.IsStatusCode(err, http.StatusRequestEntityTooLarge) {
+				half := len(docs) / 2
+				hook.sendBulk(docs[:half])
+				hook.sendBulk(docs[half:])
+				return
+			}
+			hook.observeBulk(time.Since(start), true)
+			if !canRetry || !retriableError(err) {
+				hook.completeAll(docs, err)
+				return
+			}
+			delay := hook.retryDelay(attempt, res, err)
+			hook.diagnose(logrus.InfoLevel, logrus.Fields{"attempt": attempt, "delay": delay, "error": err}, "Retrying bulk request")
+			if !hook.wait(delay) {
+				hook.completeAll(docs, err)
+				return
+			}
+			atomic.AddInt64(&hook.retried, int64(len(docs)))
+			continue
+		}
+
+		var retry []*document
+		throttled := false
+		for i, doc := range docs {
+			result := bulkItem(ret, i)
+			if result == nil {
+				// Without a result it is unknown whether the
+				// document was indexed, so it is not sent again
+				hook.complete(doc, ErrMissingBulkItem)
+				continue
+			}
+			doc.status = result.Status
+			switch {
+			case result.Status >= 200 && result.Status <= 299:
+				doc.id = result.Id
+				hook.complete(doc, nil)
+			case canRetry && retriableStatus(result.Status):
+				retry = append(retry, doc)
+				throttled = throttled || result.Status == http.StatusTooManyRequests
+			default:
+				hook.complete(doc, newBulkItemError(result))
+			}
+		}
+		hook.observeBulk(time.Since(start), throttled)
+		if len(retry) == 0 {
+			return
+		}
+
+		delay := hook.retry.delay(attempt)
+		if throttled && delay < defaultRetryAfter {
+			delay = defaultRetryAfter
+		}
+		hook.diagnose(logrus.InfoLevel, logrus.Fields{"attempt": attempt, "delay": delay, "entries": len(retry)}, "Retrying rejected entries")
+		if !hook.wait(delay) {
+			hook.completeAll(retry, hook.ctx.Err())
+			return
+		}
+		atomic.AddInt64(&hook.retried, int64(len(retry)))
+		docs = retry
+	}
+}
+
+// Receipt describes the outcome of the delivery of a single entry
+type Receipt struct {
+	// Entry is nil for entries read from the write-ahead log
+	// or replayed from the dead letter file
+	Entry *logrus.Entry
+	Index string
+	// ID is the ID of the created document
+	ID string
+	// Status is the HTTP status ElasticSearch returned for the
+	// entry or zero if the request failed as a whole
+	Status int
+	Err    error
+}
+
+// ReceiptHandler is called with the receipt of every entry
+type ReceiptHandler func(receipt Receipt)
+
+// WithReceiptHandler sets a function called with the receipt of every
+// entry delivered or failed by an asynchronous or bulk hook. For bulk
+// hooks the receipts are mapped from the items of the bulk responses.
+func WithReceiptHandler(handler ReceiptHandler) Option {
+	return func(hook *ElasticHook) {
+		hook.onReceipt = handler
+	}
+}
+
+// bulkItem returns the result of the i-th action of a bulk request
+func bulkItem(res *elastic.BulkResponse, i int) *elastic.BulkResponseItem {
+	if i >= len(res.Items) {
+		return nil
+	}
+	for _, result := range res.Items[i] {
+		return result
+	}
+	return nil
+}
+
+// bulkRequest sends the documents in a single bulk request
+func (hook *ElasticHook) bulkRequest(docs []*document) (*elastic.BulkResponse, *elastic.Response, error) {
+	var body strings.Builder
+	for _, doc := range docs {
+		lines, err := elastic.NewBulkIndexRequest().
+			Index(doc.index).
+			Type("log").
+			Doc(hook.payload(doc)).
+			Source()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, line := range lines {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+
+	start := time.Now()
+	res, err := hook.perform(elastic.PerformRequestOptions{
+		Method:      "POST",
+		Path:        "/_bulk",
+		Body:        body.String(),
+		ContentType: "application/x-ndjson",
+		Headers:     hook.opaqueHeaders(batchCorrelationID(docs)),
+	})
+	hook.observeRequest(len(docs), start, err)
+	if err != nil {
+		return nil, res, err
+	}
+
+	ret := new(elastic.BulkResponse)
+	if err := json.Unmarshal(res.Body, ret); err != nil {
+		return nil, res, err
+	}
+	return ret, res, nil
+}
+
+func newBulkItemError(item *elastic.BulkResponseItem) *BulkItemError {
+	err := &BulkItemError{
+		Index:  item.Index,
+		Status: item.Status,
+	}
+	if item.Error != nil {
+		err.Type = item.Error.Type
+		err.Reason = item.Error.Reason
+	}
+	return err
+}
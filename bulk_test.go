@@ -0,0 +1,326 @@
+package elogrus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSendBulk(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/_bulk" {
+			return false
+		}
+		var items []string
+		mu.Lock()
+		for _, doc := range readBulk(r) {
+			switch {
+			case strings.Contains(doc, "bad"):
+				items = append(items, `{"index":{"_index":"log","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+			case strings.Contains(doc, "retry") && attempts["retry"] == 0:
+				attempts["retry"]++
+				items = append(items, `{"index":{"_index":"log","status":503}}`)
+			default:
+				items = append(items, `{"index":{"_index":"log","_id":"42","status":201}}`)
+			}
+		}
+		mu.Unlock()
+		fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+		return true
+	})
+	defer server.Close()
+
+	var reported []error
+	receipts := map[string]Receipt{}
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+		WithErrorHandler(func(err error, entry *logrus.Entry) {
+			mu.Lock()
+			reported = append(reported, err)
+			mu.Unlock()
+		}),
+		WithReceiptHandler(func(receipt Receipt) {
+			mu.Lock()
+			receipts[receipt.Entry.Message] = receipt
+			mu.Unlock()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	for _, message := range []string{"ok", "retry", "bad"} {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.InfoLevel
+		entry.Message = message
+		if err := hook.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hook.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for message, status := range map[string]int{"ok": 201, "retry": 201, "bad": 400} {
+		if receipts[message].Status != status {
+			t.Errorf("Expected status %d for %s got %+v", status, message, receipts[message])
+		}
+	}
+	if receipts["ok"].ID != "42" {
+		t.Errorf("Expected ID 42 got %q", receipts["ok"].ID)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("Expected 1 reported error got %v", reported)
+	}
+	itemErr, ok := reported[0].(*BulkItemError)
+	if !ok || itemErr.Status != 400 || itemErr.Type != "mapper_parsing_exception" || itemErr.Reason != "failed to parse" {
+		t.Errorf("Unexpected error %#v", reported[0])
+	}
+	if stats := hook.Stats(); stats.Retried != 1 || stats.Sent != 2 || stats.Failed != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSendBulkTooLarge(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/_bulk" {
+			return false
+		}
+		docs := readBulk(r)
+		mu.Lock()
+		sizes = append(sizes, len(docs))
+		mu.Unlock()
+		if len(docs) > 2 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return true
+		}
+		items := strings.Repeat(`{"index":{"_id":"1","status":201}},`, len(docs))
+		fmt.Fprintf(w, `{"errors":false,"items":[%s]}`, strings.TrimSuffix(items, ","))
+		return true
+	})
+	defer server.Close()
+
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithAdaptiveBatchSize(1, 10, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+	hook.sizer.mu.Lock()
+	hook.sizer.size = 10
+	hook.sizer.mu.Unlock()
+
+	var entries []*logrus.Entry
+	for i := 0; i < 5; i++ {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.InfoLevel
+		entries = append(entries, entry)
+	}
+	if err := hook.FireBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 5 are split into 2 and 3, 3 into 1 and 2
+	expected := []int{5, 2, 3, 1, 2}
+	if fmt.Sprint(sizes) != fmt.Sprint(expected) {
+		t.Errorf("Expected requests of %v entries got %v", expected, sizes)
+	}
+	if stats := hook.Stats(); stats.Sent != 5 || stats.Failed != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if limit := hook.batchLimit(); limit != 10 {
+		t.Errorf("Expected requests too large not to shrink the batch size got %d", limit)
+	}
+}
+
+func TestSendBulkMissingItems(t *testing.T) {
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/_bulk" {
+			return false
+		}
+		readBulk(r)
+		// Only the result of the first document is returned
+		fmt.Fprint(w, `{"errors":false,"items":[{"index":{"_id":"1","status":201}}]}`)
+		return true
+	})
+	defer server.Close()
+
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.DebugLevel, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	entries := []*logrus.Entry{infoEntry(), infoEntry(), infoEntry()}
+	err = hook.FireBatch(entries)
+	bulkErr, ok := err.(*BulkError)
+	if !ok || bulkErr.Total != 3 || len(bulkErr.Errors) != 2 || bulkErr.Errors[0] != ErrMissingBulkItem {
+		t.Fatalf("Expected 2 entries without results to fail got %v", err)
+	}
+	if stats := hook.Stats(); stats.Sent != 1 || stats.Failed != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestFireBatch(t *testing.T) {
+	const batchBytes = 1000
+	var mu sync.Mutex
+	var sizes, counts []int
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/_bulk" {
+			return false
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		var items []string
+		for _, doc := range readBulk(r) {
+			if strings.Contains(doc, "bad") {
+				items = append(items, `{"index":{"_index":"log","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+			} else {
+				items = append(items, `{"index":{"_index":"log","_id":"1","status":201}}`)
+			}
+		}
+		mu.Lock()
+		sizes = append(sizes, len(body))
+		counts = append(counts, len(items))
+		mu.Unlock()
+		fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+		return true
+	})
+	defer server.Close()
+
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.InfoLevel, "log", WithBatchBytes(batchBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	var entries []*logrus.Entry
+	for _, message := range []string{"a", "b", "bad", "c", "d"} {
+		entry := infoEntry()
+		entry.Message = message + strings.Repeat(" ", 300)
+		entries = append(entries, entry)
+	}
+	debug := infoEntry()
+	debug.Level = logrus.DebugLevel
+	entries = append(entries, debug)
+
+	err = hook.FireBatch(entries)
+	bulkErr, ok := err.(*BulkError)
+	if !ok || bulkErr.Total != 5 || len(bulkErr.Errors) != 1 {
+		t.Fatalf("Expected 1 of 5 entries to fail got %v", err)
+	}
+	if itemErr, ok := bulkErr.Errors[0].(*BulkItemError); !ok || itemErr.Status != 400 {
+		t.Errorf("Unexpected error %#v", bulkErr.Errors[0])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	total := 0
+	for i, size := range sizes {
+		if size > batchBytes {
+			t.Errorf("Expected requests of at most %d bytes got %d", batchBytes, size)
+		}
+		total += counts[i]
+	}
+	if len(sizes) < 2 || total != 5 {
+		t.Errorf("Expected the 5 entries to be split into several requests got %v", counts)
+	}
+}
+
+func TestBulkFlushInterval(t *testing.T) {
+	server, client := newStubElastic(nil)
+	defer server.Close()
+
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithBatchSize(100), WithFlushInterval(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := hook.Fire(infoEntry()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if server.count() != 0 {
+		t.Fatalf("Expected the partial batch to be held back got %d documents", server.count())
+	}
+	if !waitFor(func() bool { return server.count() == 2 }) {
+		t.Fatalf("Expected the partial batch to be sent after the interval got %d documents", server.count())
+	}
+
+	// Close waits for the collecting goroutine, which stops the ticker
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := hook.Close(ctx); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Close did not return before the deadline")
+	}
+	time.Sleep(120 * time.Millisecond)
+	if server.count() != 2 {
+		t.Errorf("Expected no requests after closing got %d documents", server.count())
+	}
+}
+
+func TestBulkBatchBytes(t *testing.T) {
+	const batchBytes = 1000
+	var mu sync.Mutex
+	var sizes []int
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/_bulk" {
+			body, _ := ioutil.ReadAll(r.Body)
+			r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+			mu.Lock()
+			sizes = append(sizes, len(body))
+			mu.Unlock()
+		}
+		return false
+	})
+	defer server.Close()
+
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithBatchSize(100), WithBatchBytes(batchBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	for i := 0; i < 6; i++ {
+		entry := infoEntry()
+		entry.Message = strings.Repeat(" ", 300)
+		if err := hook.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hook.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, size := range sizes {
+		if size > batchBytes {
+			t.Errorf("Expected requests of at most %d bytes got %d", batchBytes, size)
+		}
+	}
+	if len(sizes) < 3 || server.count() != 6 {
+		t.Errorf("Expected the 6 entries to be sent early in several requests got %v", sizes)
+	}
+}
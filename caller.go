@@ -0,0 +1,83 @@
+package elogrus
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields holding the caller of an entry
+const (
+	callerFileField     = "log.origin.file.name"
+	callerLineField     = "log.origin.file.line"
+	callerFunctionField = "log.origin.function"
+)
+
+// maxCallerDepth is the number of frames searched for the caller
+const maxCallerDepth = 64
+
+// WithCallerSkip skips the number of frames above the caller reported
+// by logrus, so the caller of a wrapper around logrus is reported
+func WithCallerSkip(frames int) Option {
+	return func(hook *ElasticHook) {
+		hook.callerSkip = frames
+	}
+}
+
+// WithCallerIgnore skips the frames of functions starting with one of
+// the prefixes, e.g. "github.com/acme/log.", when reporting the caller
+func WithCallerIgnore(prefixes ...string) Option {
+	return func(hook *ElasticHook) {
+		hook.callerIgnore = append(hook.callerIgnore, prefixes...)
+	}
+}
+
+// caller returns the frame which created the entry, nil unless the
+// logger reports the caller. Frames are only skipped while the entry
+// is fired, as the stack is needed to find the frames above.
+func (hook *ElasticHook) caller(entry *logrus.Entry) *runtime.Frame {
+	if entry.Caller == nil || (hook.callerSkip == 0 && len(hook.callerIgnore) == 0) {
+		return entry.Caller
+	}
+
+	pcs := make([]uintptr, maxCallerDepth)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	found, skip := false, hook.callerSkip
+	for {
+		frame, more := frames.Next()
+		found = found || frame.Function == entry.Caller.Function
+		if found {
+			if skip > 0 {
+				skip--
+			} else if !hook.ignoresCaller(frame.Function) {
+				return &frame
+			}
+		}
+		if !more {
+			return entry.Caller
+		}
+	}
+}
+
+// ignoresCaller reports whether frames of the function are skipped
+func (hook *ElasticHook) ignoresCaller(function string) bool {
+	for _, prefix := range hook.callerIgnore {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addCaller adds the file, line and function of the
+// caller which created the entry to the message
+func (hook *ElasticHook) addCaller(msg map[string]interface{}, entry *logrus.Entry) {
+	frame := hook.caller(entry)
+	if frame == nil {
+		return
+	}
+	msg[callerFileField] = frame.File
+	msg[callerLineField] = frame.Line
+	msg[callerFunctionField] = frame.Function
+}
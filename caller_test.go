@@ -0,0 +1,47 @@
+package elogrus
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAddCaller(t *testing.T) {
+	hook := &ElasticHook{}
+	msg := createMessage(&logrus.Entry{}, hook).(map[string]interface{})
+	if _, ok := msg[callerFileField]; ok {
+		t.Error("Unexpected caller without ReportCaller")
+	}
+
+	entry := &logrus.Entry{Caller: &runtime.Frame{File: "main.go", Line: 42, Function: "main.main"}}
+	msg = createMessage(entry, hook).(map[string]interface{})
+	if msg[callerFileField] != "main.go" || msg[callerLineField] != 42 || msg[callerFunctionField] != "main.main" {
+		t.Errorf("Unexpected caller %v", msg)
+	}
+}
+
+// logWrapper mimics a wrapper around logrus reporting itself as caller
+func logWrapper(hook *ElasticHook) *runtime.Frame {
+	pc, file, line, _ := runtime.Caller(0)
+	entry := &logrus.Entry{Caller: &runtime.Frame{PC: pc, File: file, Line: line, Function: runtime.FuncForPC(pc).Name()}}
+	return hook.caller(entry)
+}
+
+func TestCallerSkip(t *testing.T) {
+	if frame := logWrapper(&ElasticHook{}); frame.Function != "github.com/derWhity/elogrus.logWrapper" {
+		t.Errorf("Unexpected caller %s", frame.Function)
+	}
+
+	hook := &ElasticHook{}
+	WithCallerSkip(1)(hook)
+	if frame := logWrapper(hook); frame.Function != "github.com/derWhity/elogrus.TestCallerSkip" {
+		t.Errorf("Unexpected caller %s", frame.Function)
+	}
+
+	hook = &ElasticHook{}
+	WithCallerIgnore("github.com/derWhity/elogrus.logWrapper")(hook)
+	if frame := logWrapper(hook); frame.Function != "github.com/derWhity/elogrus.TestCallerSkip" {
+		t.Errorf("Unexpected caller %s", frame.Function)
+	}
+}
@@ -0,0 +1,155 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cloudTimeout limits the time spent querying the instance metadata
+const cloudTimeout = time.Second
+
+// Instance metadata endpoints, variables for testing
+var (
+	awsMetadataURL   = "http://169.254.169.254"
+	gcpMetadataURL   = "http://metadata.google.internal"
+	azureMetadataURL = "http://169.254.169.254"
+)
+
+// cloudInstance describes the instance the process runs on
+type cloudInstance struct {
+	provider string
+	region   string
+	zone     string
+	id       string
+}
+
+// WithCloudMetadata queries the instance metadata of AWS, GCP and Azure
+// once while creating the hook and adds the fields cloud.provider,
+// cloud.region, cloud.availability_zone and cloud.instance.id to every
+// document. Outside of these clouds creating the hook takes up to a
+// second longer and no fields are added.
+func WithCloudMetadata() Option {
+	return func(hook *ElasticHook) {
+		instance, ok := detectCloud(&http.Client{Timeout: cloudTimeout})
+		if !ok {
+			return
+		}
+		hook.setMetadata("cloud.provider", instance.provider)
+		hook.setMetadata("cloud.region", instance.region)
+		hook.setMetadata("cloud.availability_zone", instance.zone)
+		hook.setMetadata("cloud.instance.id", instance.id)
+	}
+}
+
+// detectCloud queries the metadata endpoints of all providers at
+// once and returns the instance described by the first one answering
+func detectCloud(client *http.Client) (cloudInstance, bool) {
+	providers := []struct {
+		query func(*http.Client, string) (cloudInstance, error)
+		url   string
+	}{
+		{awsInstance, awsMetadataURL},
+		{gcpInstance, gcpMetadataURL},
+		{azureInstance, azureMetadataURL},
+	}
+	results := make(chan cloudInstance, len(providers))
+	for _, provider := range providers {
+		go func(query func(*http.Client, string) (cloudInstance, error), url string) {
+			instance, err := query(client, url)
+			if err != nil {
+				instance = cloudInstance{}
+			}
+			results <- instance
+		}(provider.query, provider.url)
+	}
+	for range providers {
+		if instance := <-results; instance.provider != "" {
+			return instance, true
+		}
+	}
+	return cloudInstance{}, false
+}
+
+// getMetadata requests the metadata and decodes the JSON response into v
+func getMetadata(client *http.Client, method, url string, header http.Header, v interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Metadata request failed with status %d", res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// awsInstance queries the instance identity document using IMDSv2
+func awsInstance(client *http.Client, url string) (cloudInstance, error) {
+	req, err := http.NewRequest("PUT", url+"/latest/api/token", nil)
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	res, err := client.Do(req)
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	token, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || res.StatusCode != http.StatusOK {
+		return cloudInstance{}, fmt.Errorf("Cannot get metadata token")
+	}
+
+	var doc struct {
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		InstanceID       string `json:"instanceId"`
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+	if err := getMetadata(client, "GET", url+"/latest/dynamic/instance-identity/document", header, &doc); err != nil {
+		return cloudInstance{}, err
+	}
+	return cloudInstance{"aws", doc.Region, doc.AvailabilityZone, doc.InstanceID}, nil
+}
+
+// gcpInstance queries the metadata server of the instance
+func gcpInstance(client *http.Client, url string) (cloudInstance, error) {
+	var doc struct {
+		ID   json.Number `json:"id"`
+		Zone string      `json:"zone"`
+	}
+	header := http.Header{"Metadata-Flavor": {"Google"}}
+	if err := getMetadata(client, "GET", url+"/computeMetadata/v1/instance/?recursive=true", header, &doc); err != nil {
+		return cloudInstance{}, err
+	}
+	// The zone looks like projects/123/zones/us-central1-a
+	zone := doc.Zone[strings.LastIndex(doc.Zone, "/")+1:]
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return cloudInstance{"gcp", region, zone, doc.ID.String()}, nil
+}
+
+// azureInstance queries the instance metadata service
+func azureInstance(client *http.Client, url string) (cloudInstance, error) {
+	var doc struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VMID     string `json:"vmId"`
+	}
+	header := http.Header{"Metadata": {"true"}}
+	if err := getMetadata(client, "GET", url+"/metadata/instance/compute?api-version=2021-02-01", header, &doc); err != nil {
+		return cloudInstance{}, err
+	}
+	return cloudInstance{"azure", doc.Location, doc.Zone, doc.VMID}, nil
+}
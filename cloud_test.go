@@ -0,0 +1,51 @@
+package elogrus
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectCloud(t *testing.T) {
+	aws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+			fmt.Fprint(w, "token")
+		case r.URL.Path == "/latest/dynamic/instance-identity/document" && r.Header.Get("X-aws-ec2-metadata-token") == "token":
+			fmt.Fprint(w, `{"region":"eu-west-1","availabilityZone":"eu-west-1a","instanceId":"i-123"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer aws.Close()
+	gcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"id":4520031799277581759,"zone":"projects/123/zones/us-central1-a"}`)
+	}))
+	defer gcp.Close()
+
+	defer func(aws, gcp, azure string) {
+		awsMetadataURL, gcpMetadataURL, azureMetadataURL = aws, gcp, azure
+	}(awsMetadataURL, gcpMetadataURL, azureMetadataURL)
+
+	awsMetadataURL, gcpMetadataURL, azureMetadataURL = aws.URL, "http://127.0.0.1:0", aws.URL
+	instance, ok := detectCloud(http.DefaultClient)
+	if !ok || instance != (cloudInstance{"aws", "eu-west-1", "eu-west-1a", "i-123"}) {
+		t.Errorf("Unexpected instance %v", instance)
+	}
+
+	awsMetadataURL, gcpMetadataURL, azureMetadataURL = "http://127.0.0.1:0", gcp.URL, "http://127.0.0.1:0"
+	instance, ok = detectCloud(http.DefaultClient)
+	if !ok || instance != (cloudInstance{"gcp", "us-central1", "us-central1-a", "4520031799277581759"}) {
+		t.Errorf("Unexpected instance %v", instance)
+	}
+
+	gcpMetadataURL = "http://127.0.0.1:0"
+	if instance, ok := detectCloud(http.DefaultClient); ok {
+		t.Errorf("Unexpected instance %v", instance)
+	}
+}
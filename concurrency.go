@@ -0,0 +1,142 @@
+package elogrus
+
+import (
+	"context"
+	"sync"
+)
+
+// WithAdaptiveConcurrency lets a bulk hook send up to max bulk requests
+// at the same time. The limit grows additively while requests succeed
+// and is halved as soon as requests are throttled or fail. Combined
+// with WithBulkSenders, the number of senders is the maximum instead.
+func WithAdaptiveConcurrency(max int) Option {
+	return func(hook *ElasticHook) {
+		if max > 0 {
+			if hook.limiter != nil && !hook.limiter.adaptive {
+				max = hook.limiter.max
+			}
+			hook.limiter = newLimiter(1, max, true)
+		}
+	}
+}
+
+// WithBulkSenders lets a bulk hook send up to n bulk requests at the
+// same time. A single sender, the default, keeps the entries in order.
+// Combined with WithAdaptiveConcurrency, n is the maximum of the
+// adaptive limit, regardless of the order of the options.
+func WithBulkSenders(n int) Option {
+	return func(hook *ElasticHook) {
+		if n <= 0 {
+			return
+		}
+		if hook.limiter != nil && hook.limiter.adaptive {
+			hook.limiter.max = n
+			return
+		}
+		hook.limiter = newLimiter(n, n, false)
+	}
+}
+
+// limiter bounds the number of bulk requests in flight, optionally
+// adapting the limit using additive increase/multiplicative decrease
+type limiter struct {
+	mu       sync.Mutex
+	limit    float64
+	max      int
+	adaptive bool
+	inflight int
+	// released is signalled whenever a request finished
+	released chan struct{}
+}
+
+func newLimiter(limit, max int, adaptive bool) *limiter {
+	return &limiter{
+		limit:    float64(limit),
+		max:      max,
+		adaptive: adaptive,
+		released: make(chan struct{}, 1),
+	}
+}
+
+// acquire waits for a free slot and reports
+// false if ctx was done in the meantime
+func (l *limiter) acquire(ctx context.Context) bool {
+	for {
+		l.mu.Lock()
+		if l.inflight < int(l.limit) {
+			l.inflight++
+			l.mu.Unlock()
+			return true
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-l.released:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release frees the slot of a finished request
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.inflight--
+	l.mu.Unlock()
+
+	select {
+	case l.released <- struct{}{}:
+	default:
+	}
+}
+
+// observe adapts the limit to the outcome of a request
+func (l *limiter) observe(failed bool) {
+	if !l.adaptive {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if failed {
+		l.limit /= 2
+	} else {
+		// Grows by about one per round of requests
+		l.limit += 1 / l.limit
+	}
+	if l.limit < 1 {
+		l.limit = 1
+	}
+	if l.limit > float64(l.max) {
+		l.limit = float64(l.max)
+	}
+}
+
+// current returns the number of requests allowed in flight
+func (l *limiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// dispatchBulk sends the batch, concurrently
+// if a concurrency limit is configured
+func (hook *ElasticHook) dispatchBulk(docs []*document) {
+	if hook.limiter == nil {
+		defer hook.recoverPanic(docs...)
+		hook.sendBulk(docs)
+		return
+	}
+	if !hook.limiter.acquire(hook.ctx) {
+		hook.completeAll(docs, hook.ctx.Err())
+		return
+	}
+
+	hook.wg.Add(1)
+	go func() {
+		defer hook.wg.Done()
+		defer hook.limiter.release()
+		defer hook.recoverPanic(docs...)
+		hook.sendBulk(docs)
+	}()
+}
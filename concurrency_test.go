@@ -0,0 +1,66 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterAIMD(t *testing.T) {
+	l := newLimiter(1, 8, true)
+	for i := 0; i < 100; i++ {
+		l.observe(false)
+	}
+	if l.current() != 8 {
+		t.Errorf("Expected limit to grow to 8 got %d", l.current())
+	}
+
+	l.observe(true)
+	if l.current() != 4 {
+		t.Errorf("Expected limit to be halved got %d", l.current())
+	}
+}
+
+func TestLimiterFixed(t *testing.T) {
+	l := newLimiter(4, 4, false)
+	l.observe(true)
+	if l.current() != 4 {
+		t.Errorf("Expected fixed limit of 4 got %d", l.current())
+	}
+}
+
+func TestLimiterAcquire(t *testing.T) {
+	l := newLimiter(1, 1, false)
+	if !l.acquire(context.TODO()) {
+		t.Fatal("Cannot acquire free slot")
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	if l.acquire(ctx) {
+		t.Fatal("Acquired more slots than allowed")
+	}
+
+	l.release()
+	if !l.acquire(context.TODO()) {
+		t.Error("Cannot acquire released slot")
+	}
+}
+
+func TestBulkSendersWithAdaptiveConcurrency(t *testing.T) {
+	for _, opts := range [][]Option{
+		{WithBulkSenders(3), WithAdaptiveConcurrency(8)},
+		{WithAdaptiveConcurrency(8), WithBulkSenders(3)},
+	} {
+		hook := &ElasticHook{}
+		for _, opt := range opts {
+			opt(hook)
+		}
+		for i := 0; i < 100; i++ {
+			hook.limiter.observe(false)
+		}
+		if !hook.limiter.adaptive || hook.limiter.current() != 3 {
+			t.Errorf("Expected adaptive limit up to 3 senders got %d", hook.limiter.current())
+		}
+	}
+}
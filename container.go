@@ -0,0 +1,71 @@
+package elogrus
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// containerImageEnv names the environment variable read for the image
+// of the container, which is not visible from within the container
+const containerImageEnv = "CONTAINER_IMAGE"
+
+// cgroupIDPattern matches the ID of a container in the cgroup paths
+// created by Docker, Kubernetes runtimes and Podman
+var cgroupIDPattern = regexp.MustCompile(`(?:/docker/|docker-|/kubepods\S*[/-]|cri-containerd-|crio-|libpod-)([0-9a-f]{64})`)
+
+// hostnameIDPattern matches the ID of a container in the source of
+// the /etc/hostname mount created for it by the container runtime
+var hostnameIDPattern = regexp.MustCompile(`/([0-9a-f]{64})/(?:userdata/)?hostname$`)
+
+// WithContainerMetadata adds the fields container.id and
+// container.image.name to every document if the process runs in a
+// container, matching the fields written by other log shippers. The ID
+// is read from the cgroup of the process or, if the cgroup namespace is
+// private, from the /etc/hostname mount of the container. The image is
+// read from the environment variable CONTAINER_IMAGE, e.g. set by the
+// deployment.
+func WithContainerMetadata() Option {
+	return func(hook *ElasticHook) {
+		id := ""
+		if content, err := ioutil.ReadFile("/proc/self/cgroup"); err == nil {
+			id = containerID(string(content))
+		}
+		if content, err := ioutil.ReadFile("/proc/self/mountinfo"); err == nil && id == "" {
+			id = mountedContainerID(string(content))
+		}
+		if id != "" {
+			hook.setMetadata("container.id", id)
+		}
+		if image := os.Getenv(containerImageEnv); image != "" {
+			hook.setMetadata("container.image.name", image)
+		}
+	}
+}
+
+// containerID returns the container ID found in
+// the cgroup of a process or an empty string
+func containerID(cgroup string) string {
+	if match := cgroupIDPattern.FindStringSubmatch(cgroup); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// mountedContainerID returns the container ID found in the /etc/hostname
+// mount listed in the mountinfo of a process or an empty string. Other
+// mounts are ignored as they may refer to other containers.
+func mountedContainerID(mountinfo string) string {
+	for _, line := range strings.Split(mountinfo, "\n") {
+		// ID, parent ID, device, root, mount point, ...
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[4] != "/etc/hostname" {
+			continue
+		}
+		if match := hostnameIDPattern.FindStringSubmatch(fields[3]); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package elogrus
+
+import (
+	"os"
+	"testing"
+)
+
+func TestContainerID(t *testing.T) {
+	id := "3f4b1c2d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809"
+	for content, expected := range map[string]string{
+		"12:memory:/docker/" + id + "\n":                               id,
+		"0::/system.slice/docker-" + id + ".scope\n":                   id,
+		"0::/kubepods/besteffort/pod1/" + id + "\n":                    id,
+		"0::/kubepods/besteffort/pod1/cri-containerd-" + id:            id,
+		"0::/user.slice/user-1000.slice/session-2.scope\n":             "",
+		"1234 56 0:52 / /etc/hostname rw - ext4 /var/lib/abc":          "",
+		"0::/system.slice/backup-" + id + ".service\n":                 "",
+		"1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n": "",
+	} {
+		if got := containerID(content); got != expected {
+			t.Errorf("Expected %q for %q got %q", expected, content, got)
+		}
+	}
+}
+
+func TestMountedContainerID(t *testing.T) {
+	id := "3f4b1c2d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809"
+	other := "9a8b7c6d5e4f30211a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7081"
+	host := "120 25 0:50 / /var/lib/docker/overlay2/" + other + "/merged rw - overlay overlay rw\n" +
+		"121 25 0:51 / /run/docker/netns/" + other + " rw - nsfs nsfs rw\n" +
+		"122 29 8:1 /var/lib/docker/containers/" + other + "/mounts/shm /var/lib/docker/containers/" + other + "/mounts/shm rw - ext4 /dev/sda1 rw\n"
+	if got := mountedContainerID(host); got != "" {
+		t.Errorf("Expected no ID for a host process got %q", got)
+	}
+
+	container := "510 480 0:60 / / rw - overlay overlay rw,lowerdir=/var/lib/docker/overlay2/l/" + other + "\n" +
+		"530 510 8:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/sda1 rw\n"
+	if got := mountedContainerID(container); got != id {
+		t.Errorf("Expected %q got %q", id, got)
+	}
+	podman := "530 510 0:45 /containers/storage/overlay-containers/" + id + "/userdata/hostname /etc/hostname rw - tmpfs tmpfs rw\n"
+	if got := mountedContainerID(podman); got != id {
+		t.Errorf("Expected %q got %q", id, got)
+	}
+}
+
+func TestWithContainerMetadata(t *testing.T) {
+	os.Setenv(containerImageEnv, "acme/app:1.0")
+	defer os.Unsetenv(containerImageEnv)
+
+	hook := &ElasticHook{}
+	WithContainerMetadata()(hook)
+	if hook.metadata["container.image.name"] != "acme/app:1.0" {
+		t.Errorf("Unexpected image %v", hook.metadata["container.image.name"])
+	}
+}
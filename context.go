@@ -0,0 +1,35 @@
+package elogrus
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ContextFieldExtractor returns fields taken from the
+// context of an entry, e.g. request or tenant IDs
+type ContextFieldExtractor func(ctx context.Context) logrus.Fields
+
+// SetContextFieldExtractor sets the function called for entries logged
+// with a context, e.g. using log.WithContext(ctx). The fields returned
+// are written like the fields of the entry, which take precedence.
+// A nil extractor disables the extraction.
+func (hook *ElasticHook) SetContextFieldExtractor(extractor ContextFieldExtractor) {
+	hook.handlerMu.Lock()
+	hook.fromContext = extractor
+	hook.handlerMu.Unlock()
+}
+
+// contextFields returns the fields extracted from the context of the entry
+func (hook *ElasticHook) contextFields(entry *logrus.Entry) logrus.Fields {
+	if entry.Context == nil {
+		return nil
+	}
+	hook.handlerMu.RLock()
+	extractor := hook.fromContext
+	hook.handlerMu.RUnlock()
+	if extractor == nil {
+		return nil
+	}
+	return extractor(entry.Context)
+}
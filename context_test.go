@@ -0,0 +1,34 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetContextFieldExtractor(t *testing.T) {
+	type key struct{}
+	hook := &ElasticHook{}
+	WithStaticFields(logrus.Fields{"tenant": "none", "env": "prod"})(hook)
+	hook.SetContextFieldExtractor(func(ctx context.Context) logrus.Fields {
+		return logrus.Fields{"tenant": ctx.Value(key{}), "request_id": "abc"}
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "acme")
+	data := hook.entryData(&logrus.Entry{Context: ctx, Data: logrus.Fields{"request_id": "def"}})
+	if data["tenant"] != "acme" || data["env"] != "prod" || data["request_id"] != "def" {
+		t.Errorf("Unexpected data %v", data)
+	}
+
+	data = hook.entryData(&logrus.Entry{Data: logrus.Fields{}})
+	if data["tenant"] != "none" {
+		t.Errorf("Unexpected data without context %v", data)
+	}
+
+	hook.SetContextFieldExtractor(nil)
+	data = hook.entryData(&logrus.Entry{Context: ctx, Data: logrus.Fields{}})
+	if data["tenant"] != "none" {
+		t.Errorf("Unexpected data without extractor %v", data)
+	}
+}
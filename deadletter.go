@@ -0,0 +1,331 @@
+package elogrus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/olivere/elastic"
+)
+
+// deadLetterField is the document field holding
+// the failure details of a dead letter
+const deadLetterField = "dead_letter"
+
+// WithDeadLetterFile makes the hook append documents which could not be
+// delivered to the file at path instead of losing them. The file is in
+// NDJSON format and can be sent to ElasticSearch using the bulk API.
+// Every document contains the failure reason in its dead_letter field.
+func WithDeadLetterFile(path string) Option {
+	return func(hook *ElasticHook) {
+		hook.deadLetters = &deadLetterFile{path: path}
+	}
+}
+
+// deadLetterFile collects documents which could not be delivered
+type deadLetterFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// deadLetter describes why a document could not be delivered
+type deadLetter struct {
+	Reason   string `json:"reason"`
+	FailedAt string `json:"failed_at"`
+}
+
+// write appends the action line and the document
+// extended by the failure details to the file
+func (f *deadLetterFile) write(doc *document, cause error) error {
+	action, err := elastic.NewBulkIndexRequest().
+		Index(doc.index).
+		Type("log").
+		Source()
+	if err != nil {
+		return err
+	}
+	details, err := json.Marshal(map[string]deadLetter{
+		deadLetterField: {
+			Reason:   cause.Error(),
+			FailedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(action[0])
+	buf.WriteByte('\n')
+	buf.Write(mergeObjects(doc.body, details))
+	buf.WriteByte('\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// mergeObjects adds the fields of the JSON object extra to the JSON
+// object body. Bodies which are no objects are returned unchanged.
+func mergeObjects(body, extra json.RawMessage) []byte {
+	body = bytes.TrimSpace(body)
+	extra = bytes.TrimSpace(extra)
+	if len(body) < 2 || body[0] != '{' || len(extra) < 2 {
+		return body
+	}
+
+	merged := make([]byte, 0, len(body)+len(extra))
+	merged = append(merged, extra[:len(extra)-1]...)
+	if rest := bytes.TrimSpace(body[1:]); len(rest) > 0 && rest[0] != '}' {
+		merged = append(merged, ',')
+		merged = append(merged, rest...)
+	} else {
+		merged = append(merged, '}')
+	}
+	return merged
+}
+
+// deadLetter writes the document to the dead letter file if configured
+func (hook *ElasticHook) deadLetter(doc *document, cause error) {
+	if hook.deadLetters == nil {
+		return
+	}
+	if err := hook.deadLetters.write(doc, cause); err != nil {
+		hook.reportError(err, doc.entry)
+	}
+}
+
+// ReplayDeadLetters sends the documents of the dead letter file at path
+// again. Documents are removed from the file once they are indexed, the
+// ones failing again are kept. Dead letters added while replaying are
+// not lost.
+func (hook *ElasticHook) ReplayDeadLetters(ctx context.Context, path string) error {
+	// Move the file out of the way, so new dead letters
+	// can be written while the old ones are replayed
+	replaying := fmt.Sprintf("%s.%d.replay", path, time.Now().UnixNano())
+	err := hook.lockDeadLetters(path, func() error {
+		return os.Rename(path, replaying)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	docs, invalid, err := readDeadLetters(replaying)
+	if err != nil {
+		if restoreErr := hook.restoreDeadLetters(path, replaying); restoreErr != nil {
+			return restoreErr
+		}
+		return err
+	}
+	failed := hook.replay(ctx, docs)
+
+	// Keep everything which was not indexed
+	err = hook.lockDeadLetters(path, func() error {
+		file := &deadLetterFile{path: path}
+		for _, doc := range docs {
+			if cause, ok := failed[doc]; ok {
+				if err := file.write(doc, cause); err != nil {
+					return err
+				}
+			}
+		}
+		return appendLines(path, invalid)
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(replaying); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Cannot replay %d of %d dead letters", len(failed), len(docs))
+	}
+	return nil
+}
+
+// replay sends the documents through the delivery pipeline of the
+// hook and returns the documents which could not be indexed
+func (hook *ElasticHook) replay(ctx context.Context, docs []*document) map[*document]error {
+	failed := make(map[*document]error)
+	if hook.mode == modeSync {
+		for _, doc := range docs {
+			if err := hook.sendDocument(doc); err != nil {
+				failed[doc] = err
+			}
+		}
+		return failed
+	}
+
+	type result struct {
+		doc *document
+		err error
+	}
+	results := make(chan result, len(docs))
+	pending := make(map[*document]bool)
+	for _, doc := range docs {
+		doc := doc
+		doc.done = func(err error) {
+			results <- result{doc, err}
+		}
+		if err := hook.enqueueReplay(ctx, doc); err != nil {
+			failed[doc] = err
+			continue
+		}
+		pending[doc] = true
+	}
+
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				failed[r.doc] = r.err
+			}
+			delete(pending, r.doc)
+		case <-ctx.Done():
+			for doc := range pending {
+				failed[doc] = ctx.Err()
+			}
+			return failed
+		}
+	}
+	return failed
+}
+
+// enqueueReplay queues a replayed document unless the hook is closed
+func (hook *ElasticHook) enqueueReplay(ctx context.Context, doc *document) error {
+	hook.closeMu.RLock()
+	defer hook.closeMu.RUnlock()
+	if hook.closed {
+		return ErrHookClosed
+	}
+	if err := hook.queue.pushWait(ctx, doc); err != nil {
+		return err
+	}
+	atomic.AddInt64(&hook.enqueued, 1)
+	return nil
+}
+
+// lockDeadLetters runs fn while no dead letters are
+// written to path by the hook itself
+func (hook *ElasticHook) lockDeadLetters(path string, fn func() error) error {
+	if hook.deadLetters != nil && hook.deadLetters.path == path {
+		hook.deadLetters.mu.Lock()
+		defer hook.deadLetters.mu.Unlock()
+	}
+	return fn()
+}
+
+// restoreDeadLetters moves the dead letters which could not be replayed
+// back to path, ahead of the ones written while replaying
+func (hook *ElasticHook) restoreDeadLetters(path, replaying string) error {
+	return hook.lockDeadLetters(path, func() error {
+		written, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(written) > 0 {
+			file, err := os.OpenFile(replaying, os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = file.Write(written)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return os.Rename(replaying, path)
+	})
+}
+
+// readDeadLetters parses a dead letter file. Lines which cannot be
+// parsed are returned separately, so they do not get lost.
+func readDeadLetters(path string) ([]*document, [][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var docs []*document
+	var invalid [][]byte
+	var action map[string]struct {
+		Index string `json:"_index"`
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, walSegmentSize)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if action == nil {
+			if err := json.Unmarshal(line, &action); err != nil || action["index"].Index == "" {
+				action = nil
+				invalid = append(invalid, line)
+			}
+			continue
+		}
+
+		body, err := removeField(line, deadLetterField)
+		if err != nil {
+			invalid = append(invalid, line)
+		} else {
+			docs = append(docs, &document{
+				index:   action["index"].Index,
+				body:    body,
+				created: time.Now(),
+			})
+		}
+		action = nil
+	}
+	return docs, invalid, scanner.Err()
+}
+
+// removeField removes a top level field from a JSON object
+func removeField(body []byte, field string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, field)
+	return json.Marshal(fields)
+}
+
+func appendLines(path string, lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
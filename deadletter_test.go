@@ -0,0 +1,86 @@
+package elogrus
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeObjects(t *testing.T) {
+	extra := []byte(`{"dead_letter":{"reason":"failed"}}`)
+	for body, expected := range map[string]string{
+		`{"Message":"hello"}`: `{"dead_letter":{"reason":"failed"},"Message":"hello"}`,
+		`{}`:                  `{"dead_letter":{"reason":"failed"}}`,
+		` { } `:               `{"dead_letter":{"reason":"failed"}}`,
+		`"text"`:              `"text"`,
+	} {
+		if merged := string(mergeObjects([]byte(body), extra)); merged != expected {
+			t.Errorf("Wrong merge of %s: expected %s got %s", body, expected, merged)
+		}
+	}
+}
+
+func TestReadDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.ndjson")
+	content := `{"index":{"_index":"mylog","_type":"log"}}
+{"dead_letter":{"reason":"failed"},"Message":"hello"}
+not json
+{"index":{"_index":"other","_type":"log"}}
+{"Message":"world"}
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, invalid, err := readDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents got %d", len(docs))
+	}
+	if docs[0].index != "mylog" || string(docs[0].body) != `{"Message":"hello"}` {
+		t.Errorf("Wrong first document: %s %s", docs[0].index, docs[0].body)
+	}
+	if docs[1].index != "other" || string(docs[1].body) != `{"Message":"world"}` {
+		t.Errorf("Wrong second document: %s %s", docs[1].index, docs[1].body)
+	}
+	if len(invalid) != 1 || string(invalid[0]) != "not json" {
+		t.Errorf("Expected invalid line to be kept got %q", invalid)
+	}
+}
+
+func TestReplayDeadLettersUnreadable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.ndjson")
+	// A line exceeding the maximum line length fails reading the file
+	content := `{"index":{"_index":"mylog","_type":"log"}}` + "\n" +
+		`{"Message":"` + strings.Repeat("x", walSegmentSize) + `"}` + "\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hook := &ElasticHook{mode: modeSync}
+	WithDeadLetterFile(path)(hook)
+	if err := hook.ReplayDeadLetters(context.TODO(), path); err == nil {
+		t.Error("Expected replay to fail")
+	}
+	if written, _ := ioutil.ReadFile(path); string(written) != content {
+		t.Error("Expected the dead letters to be restored")
+	}
+	if names, _ := filepath.Glob(path + ".*.replay"); len(names) != 0 {
+		t.Errorf("Expected no replay files to be left got %v", names)
+	}
+
+	// Dead letters written while replaying follow the restored ones
+	replaying := path + ".1.replay"
+	ioutil.WriteFile(replaying, []byte("old\n"), 0644)
+	ioutil.WriteFile(path, []byte("new\n"), 0644)
+	if err := hook.restoreDeadLetters(path, replaying); err != nil {
+		t.Fatal(err)
+	}
+	if written, _ := ioutil.ReadFile(path); string(written) != "old\nnew\n" {
+		t.Errorf("Unexpected dead letters %q", written)
+	}
+}
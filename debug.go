@@ -0,0 +1,78 @@
+package elogrus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (m deliveryMode) String() string {
+	switch m {
+	case modeAsync:
+		return "async"
+	case modeBulk:
+		return "bulk"
+	}
+	return "sync"
+}
+
+// DebugDump returns a human readable description of the pipeline state
+// and the configuration of the hook, e.g. to attach to bug reports
+func (hook *ElasticHook) DebugDump() string {
+	var b strings.Builder
+	stats := hook.Stats()
+	health := hook.Health()
+
+	fmt.Fprintf(&b, "elogrus %s hook for host %q\n", hook.mode, hook.hostname())
+	if hook.index != nil {
+		fmt.Fprintf(&b, "  index:            %s\n", hook.index())
+	}
+	fmt.Fprintf(&b, "  version:          %s\n", moduleVersion())
+	fmt.Fprintf(&b, "  health:           %s\n", health.Status)
+	fmt.Fprintf(&b, "  circuit:          %s\n", hook.breaker.current())
+
+	b.WriteString("pipeline\n")
+	if hook.mode == modeAsync {
+		fmt.Fprintf(&b, "  workers:          %d\n", hook.workers)
+	}
+	if hook.limiter != nil {
+		fmt.Fprintf(&b, "  bulk senders:     %d\n", hook.limiter.current())
+	}
+	fmt.Fprintf(&b, "  queued:           %d of %d\n", stats.Queued, hook.queueSize)
+	fmt.Fprintf(&b, "  oldest queued:    %s\n", hook.OldestQueuedAge())
+	fmt.Fprintf(&b, "  in flight:        %d\n", hook.InFlight())
+	fmt.Fprintf(&b, "  submitted:        %d\n", stats.Submitted)
+	fmt.Fprintf(&b, "  sent:             %d\n", stats.Sent)
+	fmt.Fprintf(&b, "  retried:          %d\n", stats.Retried)
+	fmt.Fprintf(&b, "  failed:           %d\n", stats.Failed)
+	fmt.Fprintf(&b, "  dropped:          %d\n", stats.Dropped)
+	if !stats.LastSuccess.IsZero() {
+		fmt.Fprintf(&b, "  last success:     %s\n", stats.LastSuccess.Format(time.RFC3339))
+	}
+
+	b.WriteString("configuration\n")
+	fmt.Fprintf(&b, "  drop policy:      %s\n", hook.dropPolicy)
+	fmt.Fprintf(&b, "  queue bytes:      %d\n", hook.queueBytes)
+	fmt.Fprintf(&b, "  batch size:       %d\n", hook.batchLimit())
+	fmt.Fprintf(&b, "  batch bytes:      %d\n", hook.batchBytes)
+	fmt.Fprintf(&b, "  flush interval:   %s\n", hook.flushInterval)
+	fmt.Fprintf(&b, "  retry:            %+v\n", hook.retry)
+	fmt.Fprintf(&b, "  request timeout:  %s\n", hook.timeout)
+	if hook.walDir != "" {
+		fmt.Fprintf(&b, "  write-ahead log:  %s\n", hook.walDir)
+	}
+	if hook.deadLetters != nil {
+		fmt.Fprintf(&b, "  dead letters:     %s\n", hook.deadLetters.path)
+	}
+
+	hook.statsMu.Lock()
+	recent := append([]timedError(nil), hook.recentErrors...)
+	hook.statsMu.Unlock()
+	if len(recent) > 0 {
+		b.WriteString("recent errors\n")
+		for _, e := range recent {
+			fmt.Fprintf(&b, "  %s %s\n", e.time.Format(time.RFC3339), e.err)
+		}
+	}
+	return b.String()
+}
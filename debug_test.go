@@ -0,0 +1,18 @@
+package elogrus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDebugDump(t *testing.T) {
+	hook := &ElasticHook{host: "localhost", index: func() string { return "log" }}
+	hook.complete(&document{}, ErrQueueFull)
+
+	dump := hook.DebugDump()
+	for _, expected := range []string{"sync hook", "index:            log", "failed:           1", ErrQueueFull.Error()} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("Expected %q in dump:\n%s", expected, dump)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package elogrus
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+)
+
+// diagnosticField marks the messages about the hook's own operation.
+// Entries carrying it are ignored by Fire, FireAsync and FireBatch, so
+// the hook does not send its own messages if the logger routes them
+// back to it.
+const diagnosticField = "elogrus_diagnostic"
+
+// WithDiagnosticLogger makes the hook log messages about its own
+// operation, like retries, state changes of the circuit breaker and
+// dropped entries, to the logger. The logger may use the hook itself.
+func WithDiagnosticLogger(logger *logrus.Logger) Option {
+	return func(hook *ElasticHook) {
+		hook.diagnostics = logger
+	}
+}
+
+// WithDiagnosticWriter makes the hook write messages
+// about its own operation as text to w
+func WithDiagnosticWriter(w io.Writer) Option {
+	logger := logrus.New()
+	logger.Out = w
+	logger.Level = logrus.DebugLevel
+	return WithDiagnosticLogger(logger)
+}
+
+// diagnose logs a message about the operation of the hook
+func (hook *ElasticHook) diagnose(level logrus.Level, fields logrus.Fields, msg string) {
+	if hook.diagnostics == nil {
+		return
+	}
+	hook.diagnostics.WithField(diagnosticField, true).WithFields(fields).Log(level, msg)
+}
+
+// isDiagnostic reports whether the entry
+// is a message about the hook's operation
+func isDiagnostic(entry *logrus.Entry) bool {
+	_, ok := entry.Data[diagnosticField]
+	return ok
+}
@@ -0,0 +1,58 @@
+package elogrus
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDiagnostics(t *testing.T) {
+	var out bytes.Buffer
+	hook := &ElasticHook{}
+	WithDiagnosticWriter(&out)(hook)
+
+	hook.reportDrop(&document{}, DropReasonQueueFull)
+	if !strings.Contains(out.String(), "Entry dropped") {
+		t.Errorf("Expected drop to be logged got %q", out.String())
+	}
+}
+
+func TestDiagnosticsRecursion(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+	hook := &ElasticHook{closed: true}
+	logger.AddHook(hook)
+	WithDiagnosticLogger(logger)(hook)
+
+	// The closed hook fails every entry not ignored
+	entry := logger.WithField(diagnosticField, true)
+	if err := hook.Fire(entry); err != nil {
+		t.Errorf("Expected diagnostic entry to be ignored got %s", err)
+	}
+	hook.reportDrop(&document{}, DropReasonQueueFull)
+}
+
+func TestDiagnosticsSkipped(t *testing.T) {
+	server, client := newStubElastic(nil)
+	defer server.Close()
+	hook, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	entry := infoEntry().WithField(diagnosticField, true)
+	entry.Level = logrus.InfoLevel
+	if err := <-hook.FireAsync(entry); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := hook.FireBatch([]*logrus.Entry{entry}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if server.count() != 0 {
+		t.Errorf("Expected diagnostic entries not to be sent got %d documents", server.count())
+	}
+}
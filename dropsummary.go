@@ -0,0 +1,108 @@
+package elogrus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithDropSummary makes the hook send an entry summarizing the entries
+// dropped during the last interval, per level, so the loss is visible
+// in the index itself. Summaries bypass the queue.
+func WithDropSummary(interval time.Duration) Option {
+	return func(hook *ElasticHook) {
+		if interval > 0 {
+			hook.dropSummary = &dropCounter{counts: map[string]int{}}
+			hook.dropSummaryInterval = interval
+		}
+	}
+}
+
+// dropCounter counts the dropped entries per level
+type dropCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+	since  time.Time
+}
+
+func (c *dropCounter) add(entry *logrus.Entry) {
+	level := "unknown"
+	if entry != nil {
+		level = entry.Level.String()
+	}
+	c.mu.Lock()
+	if len(c.counts) == 0 {
+		c.since = time.Now()
+	}
+	c.counts[level]++
+	c.mu.Unlock()
+}
+
+// reset returns the counts and the time of the first drop
+// since the last call and starts counting from zero
+func (c *dropCounter) reset() (map[string]int, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts, since := c.counts, c.since
+	c.counts = map[string]int{}
+	return counts, since
+}
+
+// runDropSummary sends a summary of the dropped entries
+// every drop summary interval until the hook is closed
+func (hook *ElasticHook) runDropSummary() {
+	defer hook.wg.Done()
+
+	logger := logrus.New()
+	ticker := time.NewTicker(hook.dropSummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			hook.sendDropSummary(logger)
+		case <-hook.closing:
+			hook.sendDropSummary(logger)
+			return
+		case <-hook.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendDropSummary sends an entry per level with the number
+// of entries dropped since the last summary
+func (hook *ElasticHook) sendDropSummary(logger *logrus.Logger) {
+	counts, since := hook.dropSummary.reset()
+	now := time.Now()
+
+	levels := make([]string, 0, len(counts))
+	for level := range counts {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+	for _, level := range levels {
+		entry := logrus.NewEntry(logger).WithFields(logrus.Fields{
+			"dropped":       counts[level],
+			"dropped_level": level,
+			"dropped_from":  since.UTC().Format(time.RFC3339Nano),
+			"dropped_to":    now.UTC().Format(time.RFC3339Nano),
+		})
+		entry.Time = now
+		entry.Level = logrus.WarnLevel
+		entry.Message = fmt.Sprintf("Dropped %d %s entries between %s and %s on host %s",
+			counts[level], level, since.Format(time.RFC3339), now.Format(time.RFC3339), hook.hostname())
+
+		doc, err := hook.newDocument(entry, hook.index())
+		if err == ErrSkipEntry {
+			continue
+		}
+		if err != nil {
+			hook.reportError(err, entry)
+			continue
+		}
+		hook.deliver(doc)
+	}
+}
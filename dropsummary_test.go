@@ -0,0 +1,86 @@
+package elogrus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDropCounter(t *testing.T) {
+	c := &dropCounter{counts: map[string]int{}}
+	c.add(&logrus.Entry{Level: logrus.DebugLevel})
+	c.add(&logrus.Entry{Level: logrus.DebugLevel})
+	c.add(nil)
+
+	counts, since := c.reset()
+	if counts["debug"] != 2 || counts["unknown"] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+	if since.IsZero() {
+		t.Error("Expected time of the first drop")
+	}
+	if counts, _ := c.reset(); len(counts) != 0 {
+		t.Errorf("Expected counts to be reset got %v", counts)
+	}
+}
+
+func TestDropSummary(t *testing.T) {
+	var blocked int32
+	release := make(chan struct{})
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+			if !strings.Contains(string(body), "dropped_level") {
+				// Regular entries wait until the summary was sent
+				atomic.AddInt32(&blocked, 1)
+				<-release
+			}
+		}
+		return false
+	})
+	defer server.Close()
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithWorkers(1), WithQueueSize(1), WithDropSummary(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+	defer close(release)
+
+	// The first entry is sent, the second queued and the others dropped
+	hook.Fire(infoEntry())
+	if !waitFor(func() bool { return atomic.LoadInt32(&blocked) == 1 }) {
+		t.Fatal("Expected the first entry to be sent")
+	}
+	for i := 0; i < 3; i++ {
+		hook.Fire(infoEntry())
+	}
+
+	var summary string
+	found := waitFor(func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		for _, doc := range server.docs {
+			if strings.Contains(doc, "dropped_level") {
+				summary = doc
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		t.Fatal("Expected a summary of the dropped entries to be indexed")
+	}
+	for _, expected := range []string{`"dropped":2`, `"dropped_level":"info"`, `"Message":"Dropped 2 info entries between `} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected %s in the summary %s", expected, summary)
+		}
+	}
+}
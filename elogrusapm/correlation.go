@@ -0,0 +1,34 @@
+// Package elogrusapm correlates the entries sent by an elogrus hook with
+// the transactions of the Elastic APM agent. It is a separate package,
+// so only applications using it depend on the agent.
+package elogrusapm
+
+import (
+	"context"
+
+	"github.com/derWhity/elogrus"
+	"go.elastic.co/apm"
+)
+
+// Correlation returns an option adding the fields trace.id,
+// transaction.id and span.id of the APM transaction and span of the
+// context to the documents of entries logged with a context, e.g.
+// using log.WithContext(ctx). The APM UI shows the logs of a
+// transaction using these fields.
+func Correlation() elogrus.Option {
+	return elogrus.WithContextMetadata(func(ctx context.Context) map[string]interface{} {
+		tx := apm.TransactionFromContext(ctx)
+		if tx == nil {
+			return nil
+		}
+		traceContext := tx.TraceContext()
+		fields := map[string]interface{}{
+			"trace.id":       traceContext.Trace.String(),
+			"transaction.id": traceContext.Span.String(),
+		}
+		if span := apm.SpanFromContext(ctx); span != nil {
+			fields["span.id"] = span.TraceContext().Span.String()
+		}
+		return fields
+	})
+}
@@ -0,0 +1,38 @@
+package elogrusapm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/derWhity/elogrus"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/transport/transporttest"
+)
+
+func TestCorrelation(t *testing.T) {
+	tracer, _ := transporttest.NewRecorderTracer()
+	defer tracer.Close()
+
+	hook := &elogrus.ElasticHook{}
+	Correlation()(hook)
+	create := elogrus.LogstashMessage("")
+
+	msg := create(&logrus.Entry{Context: context.Background()}, hook).(logrus.Fields)
+	if _, ok := msg["trace.id"]; ok {
+		t.Error("Unexpected trace ID without transaction")
+	}
+
+	tx := tracer.StartTransaction("request", "request")
+	defer tx.End()
+	ctx := apm.ContextWithTransaction(context.Background(), tx)
+	span, ctx := apm.StartSpan(ctx, "query", "db")
+	defer span.End()
+
+	msg = create(&logrus.Entry{Context: ctx}, hook).(logrus.Fields)
+	if msg["trace.id"] != tx.TraceContext().Trace.String() ||
+		msg["transaction.id"] != tx.TraceContext().Span.String() ||
+		msg["span.id"] != span.TraceContext().Span.String() {
+		t.Errorf("Unexpected correlation fields %v", msg)
+	}
+}
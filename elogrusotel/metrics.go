@@ -0,0 +1,84 @@
+// Package elogrusotel reports the deliveries of an elogrus hook using
+// OpenTelemetry. It is a separate package, so only applications using
+// it depend on OpenTelemetry.
+package elogrusotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/derWhity/elogrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// instruments records the requests and flushes of a hook
+type instruments struct {
+	requests metric.Float64Histogram
+	flushes  metric.Float64Histogram
+}
+
+// InstrumentMetrics registers metric instruments with the meter
+// reporting the entries shipped, failed and dropped by the hook,
+// the queue depth and the duration of requests and flushes
+func InstrumentMetrics(hook *elogrus.ElasticHook, meter metric.Meter) error {
+	shipped, err := meter.Int64ObservableCounter("elogrus.records.shipped",
+		metric.WithDescription("Number of log records indexed"))
+	if err != nil {
+		return err
+	}
+	failed, err := meter.Int64ObservableCounter("elogrus.records.failed",
+		metric.WithDescription("Number of log records which could not be delivered"))
+	if err != nil {
+		return err
+	}
+	dropped, err := meter.Int64ObservableCounter("elogrus.records.dropped",
+		metric.WithDescription("Number of log records discarded because the queue was full"))
+	if err != nil {
+		return err
+	}
+	queued, err := meter.Int64ObservableGauge("elogrus.queue.depth",
+		metric.WithDescription("Number of log records waiting to be sent"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats := hook.Stats()
+		o.ObserveInt64(shipped, stats.Sent)
+		o.ObserveInt64(failed, stats.Failed)
+		o.ObserveInt64(dropped, stats.Dropped)
+		o.ObserveInt64(queued, int64(stats.Queued))
+		return nil
+	}, shipped, failed, dropped, queued)
+	if err != nil {
+		return err
+	}
+
+	i := new(instruments)
+	if i.requests, err = meter.Float64Histogram("elogrus.request.duration",
+		metric.WithDescription("Time taken by the requests sent to ElasticSearch"),
+		metric.WithUnit("s")); err != nil {
+		return err
+	}
+	if i.flushes, err = meter.Float64Histogram("elogrus.flush.duration",
+		metric.WithDescription("Time taken by flushes of the hook"),
+		metric.WithUnit("s")); err != nil {
+		return err
+	}
+	hook.AddObserver(i)
+	return nil
+}
+
+// ObserveRequest implements elogrus.Observer
+func (i *instruments) ObserveRequest(entries int, latency time.Duration, err error) {
+	i.requests.Record(context.Background(), latency.Seconds(), metric.WithAttributes(
+		attribute.Bool("error", err != nil),
+	))
+}
+
+// ObserveFlush implements elogrus.FlushObserver
+func (i *instruments) ObserveFlush(latency time.Duration, err error) {
+	i.flushes.Record(context.Background(), latency.Seconds(), metric.WithAttributes(
+		attribute.Bool("error", err != nil),
+	))
+}
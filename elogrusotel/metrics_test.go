@@ -0,0 +1,103 @@
+package elogrusotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derWhity/elogrus"
+	"github.com/olivere/elastic"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newHook returns a synchronous hook sending to
+// a stub ElasticSearch accepting every document
+func newHook(t *testing.T, opts ...elogrus.Option) *elogrus.ElasticHook {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "HEAD":
+		case "POST":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"_id":"1","result":"created"}`)
+		default:
+			fmt.Fprint(w, `{"acknowledged":true}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := elastic.NewClient(
+		elastic.SetURL(server.URL),
+		elastic.SetHealthcheck(false),
+		elastic.SetSniff(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := elogrus.NewElasticHook(client, "localhost", logrus.DebugLevel, "log", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { hook.Close(context.TODO()) })
+	return hook
+}
+
+func TestInstrumentMetrics(t *testing.T) {
+	hook := newHook(t)
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	if err := InstrumentMetrics(hook, provider.Meter("elogrus")); err != nil {
+		t.Fatalf("Cannot register instruments: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.InfoLevel
+		if err := hook.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hook.Flush(); err != nil {
+		t.Errorf("Unexpected flush error: %s", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	for name, expected := range map[string]int64{
+		"elogrus.records.shipped": 2,
+		"elogrus.records.failed":  0,
+		"elogrus.records.dropped": 0,
+	} {
+		sum, ok := metrics[name].(metricdata.Sum[int64])
+		if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != expected {
+			t.Errorf("Expected %d for %s got %#v", expected, name, metrics[name])
+		}
+	}
+	if gauge, ok := metrics["elogrus.queue.depth"].(metricdata.Gauge[int64]); !ok || len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 0 {
+		t.Errorf("Unexpected queue depth %#v", metrics["elogrus.queue.depth"])
+	}
+	for name, expected := range map[string]uint64{
+		"elogrus.request.duration": 2,
+		"elogrus.flush.duration":   1,
+	} {
+		histogram, ok := metrics[name].(metricdata.Histogram[float64])
+		if !ok || len(histogram.DataPoints) != 1 || histogram.DataPoints[0].Count != expected {
+			t.Fatalf("Expected %d recordings of %s got %#v", expected, name, metrics[name])
+		}
+		if v, ok := histogram.DataPoints[0].Attributes.Value(attribute.Key("error")); !ok || v.AsBool() {
+			t.Errorf("Expected %s to be recorded without error", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package elogrusotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/derWhity/elogrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer is an elogrus.Tracer reporting the
+// operations of a hook as OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ elogrus.Tracer = (*Tracer)(nil)
+
+// NewTracer creates a Tracer creating spans using tracer. Pass it to
+// the hook using elogrus.WithTracer.
+func NewTracer(tracer trace.Tracer) *Tracer {
+	return &Tracer{tracer: tracer}
+}
+
+// Start implements elogrus.Tracer
+func (t *Tracer) Start(ctx context.Context, operation string, attributes map[string]interface{}) func(err error) {
+	attrs := make([]attribute.KeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		attrs = append(attrs, attributeOf("elogrus."+key, value))
+	}
+
+	_, span := t.tracer.Start(ctx, operation, trace.WithAttributes(attrs...))
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// attributeOf converts a value to an attribute of the matching type
+func attributeOf(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
+
+// TraceContext returns an option adding the fields trace.id and span.id
+// to the documents of entries logged with a context holding a valid
+// span, e.g. using log.WithContext(ctx), linking logs and traces
+func TraceContext() elogrus.Option {
+	return elogrus.WithContextMetadata(func(ctx context.Context) map[string]interface{} {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return nil
+		}
+		return map[string]interface{}{
+			"trace.id": sc.TraceID().String(),
+			"span.id":  sc.SpanID().String(),
+		}
+	})
+}
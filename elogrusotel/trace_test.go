@@ -0,0 +1,88 @@
+package elogrusotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/derWhity/elogrus"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := NewTracer(provider.Tracer("elogrus"))
+
+	hook := newHook(t, elogrus.WithTracer(tracer))
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.InfoLevel
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	end := tracer.Start(context.Background(), "elogrus.retry", map[string]interface{}{"attempt": 2})
+	end(errors.New("Boom"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans got %d", len(spans))
+	}
+	fire, retry := spans[0], spans[1]
+	attrs := attribute.NewSet(fire.Attributes()...)
+	if index, _ := attrs.Value("elogrus.index"); fire.Name() != "elogrus.fire" || index.AsString() != "log" {
+		t.Errorf("Unexpected span %s with %v", fire.Name(), fire.Attributes())
+	}
+	if level, _ := attrs.Value("elogrus.level"); level.AsString() != "info" {
+		t.Errorf("Unexpected level %v", level)
+	}
+	if fire.Status().Code != codes.Unset {
+		t.Errorf("Unexpected status %v", fire.Status())
+	}
+
+	attrs = attribute.NewSet(retry.Attributes()...)
+	if attempt, _ := attrs.Value("elogrus.attempt"); retry.Name() != "elogrus.retry" || attempt.AsInt64() != 2 {
+		t.Errorf("Unexpected span %s with %v", retry.Name(), retry.Attributes())
+	}
+	if status := retry.Status(); status.Code != codes.Error || status.Description != "Boom" {
+		t.Errorf("Expected error status got %v", status)
+	}
+	if events := retry.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("Expected the error to be recorded got %v", events)
+	}
+}
+
+func TestAttributeOf(t *testing.T) {
+	if kv := attributeOf("entries", 3); kv.Value.Type() != attribute.INT64 || kv.Value.AsInt64() != 3 {
+		t.Errorf("Unexpected attribute %v", kv)
+	}
+	if kv := attributeOf("index", "log"); kv.Value.AsString() != "log" {
+		t.Errorf("Unexpected attribute %v", kv)
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	hook := &elogrus.ElasticHook{}
+	TraceContext()(hook)
+	create := elogrus.LogstashMessage("")
+
+	msg := create(&logrus.Entry{Context: context.Background()}, hook).(logrus.Fields)
+	if _, ok := msg["trace.id"]; ok {
+		t.Error("Unexpected trace ID without span")
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	msg = create(&logrus.Entry{Context: ctx}, hook).(logrus.Fields)
+	if msg["trace.id"] != traceID.String() || msg["span.id"] != spanID.String() {
+		t.Errorf("Unexpected trace fields %v", msg)
+	}
+}
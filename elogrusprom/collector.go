@@ -0,0 +1,104 @@
+// Package elogrusprom exposes the metrics of an elogrus hook to
+// Prometheus. It is a separate package, so only applications using it
+// depend on the Prometheus client.
+package elogrusprom
+
+import (
+	"time"
+
+	"github.com/derWhity/elogrus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector exposing the
+// counters and request measurements of a hook
+type Collector struct {
+	hook *elogrus.ElasticHook
+
+	submitted *prometheus.Desc
+	sent      *prometheus.Desc
+	retried   *prometheus.Desc
+	failed    *prometheus.Desc
+	dropped   *prometheus.Desc
+	queued    *prometheus.Desc
+
+	requests  *prometheus.CounterVec
+	latency   prometheus.Histogram
+	batchSize prometheus.Histogram
+}
+
+// NewCollector creates a collector for the hook. All metric names
+// are prefixed with the namespace. The collector still needs to be
+// registered, e.g. using prometheus.MustRegister.
+func NewCollector(hook *elogrus.ElasticHook, namespace string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+	}
+
+	c := &Collector{
+		hook:      hook,
+		submitted: desc("entries_submitted_total", "Number of entries fired"),
+		sent:      desc("entries_sent_total", "Number of entries indexed"),
+		retried:   desc("entries_retried_total", "Number of times entries were sent again"),
+		failed:    desc("entries_failed_total", "Number of entries which could not be delivered"),
+		dropped:   desc("entries_dropped_total", "Number of entries discarded because the queue was full"),
+		queued:    desc("queue_depth", "Number of entries waiting to be sent"),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests sent to ElasticSearch",
+		}, []string{"result"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken by the requests sent to ElasticSearch",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_entries",
+			Help:      "Number of entries sent in a single request",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}),
+	}
+	hook.AddObserver(c)
+	return c
+}
+
+// ObserveRequest implements elogrus.Observer
+func (c *Collector) ObserveRequest(entries int, latency time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	c.requests.WithLabelValues(result).Inc()
+	c.latency.Observe(latency.Seconds())
+	c.batchSize.Observe(float64(entries))
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.submitted
+	ch <- c.sent
+	ch <- c.retried
+	ch <- c.failed
+	ch <- c.dropped
+	ch <- c.queued
+	c.requests.Describe(ch)
+	c.latency.Describe(ch)
+	c.batchSize.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.hook.Stats()
+	ch <- prometheus.MustNewConstMetric(c.submitted, prometheus.CounterValue, float64(stats.Submitted))
+	ch <- prometheus.MustNewConstMetric(c.sent, prometheus.CounterValue, float64(stats.Sent))
+	ch <- prometheus.MustNewConstMetric(c.retried, prometheus.CounterValue, float64(stats.Retried))
+	ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(stats.Failed))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(stats.Queued))
+	c.requests.Collect(ch)
+	c.latency.Collect(ch)
+	c.batchSize.Collect(ch)
+}
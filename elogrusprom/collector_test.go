@@ -0,0 +1,37 @@
+package elogrusprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/derWhity/elogrus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(&elogrus.ElasticHook{}, "elogrus")
+	c.ObserveRequest(10, time.Millisecond, nil)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Cannot gather metrics: %s", err)
+	}
+
+	names := map[string]bool{}
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	for _, name := range []string{
+		"elogrus_entries_sent_total",
+		"elogrus_queue_depth",
+		"elogrus_requests_total",
+		"elogrus_request_duration_seconds",
+		"elogrus_request_entries",
+	} {
+		if !names[name] {
+			t.Errorf("Metric %s is missing", name)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package elogrusstatsd reports the deliveries of an elogrus hook to
+// StatsD or DogStatsD. It does not depend on a specific client, the
+// DogStatsD client of github.com/DataDog/datadog-go implements Client.
+package elogrusstatsd
+
+import (
+	"context"
+	"time"
+
+	"github.com/derWhity/elogrus"
+)
+
+// Client is the subset of a StatsD client used by the emitter
+type Client interface {
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Count(name string, value int64, tags []string, rate float64) error
+	Timing(name string, value time.Duration, tags []string, rate float64) error
+}
+
+// Emitter sends the metrics of a hook to a StatsD client
+type Emitter struct {
+	hook   *elogrus.ElasticHook
+	client Client
+	prefix string
+	tags   []string
+	// last holds the counters reported by the previous run
+	last elogrus.Stats
+}
+
+// NewEmitter creates an emitter for the hook. The names of all
+// metrics are prefixed with prefix, e.g. "elogrus.", and carry
+// the given tags. Request latencies and errors are sent right away,
+// Run sends the queue depth and failure counts periodically.
+func NewEmitter(hook *elogrus.ElasticHook, client Client, prefix string, tags ...string) *Emitter {
+	e := &Emitter{
+		hook:   hook,
+		client: client,
+		prefix: prefix,
+		tags:   tags,
+	}
+	hook.AddObserver(e)
+	return e
+}
+
+// ObserveRequest implements elogrus.Observer
+func (e *Emitter) ObserveRequest(entries int, latency time.Duration, err error) {
+	e.client.Timing(e.prefix+"request.latency", latency, e.tags, 1)
+	e.client.Count(e.prefix+"request.entries", int64(entries), e.tags, 1)
+	if err != nil {
+		e.client.Count(e.prefix+"request.errors", 1, e.tags, 1)
+	}
+}
+
+// Run sends the queue depth and the number of failed and dropped
+// entries every interval until ctx is done
+func (e *Emitter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.emit()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// emit sends the current queue depth and
+// the counters changed since the last call
+func (e *Emitter) emit() {
+	stats := e.hook.Stats()
+	e.client.Gauge(e.prefix+"queue.depth", float64(stats.Queued), e.tags, 1)
+	e.client.Count(e.prefix+"entries.failed", stats.Failed-e.last.Failed, e.tags, 1)
+	e.client.Count(e.prefix+"entries.dropped", stats.Dropped-e.last.Dropped, e.tags, 1)
+	e.last = stats
+}
@@ -0,0 +1,47 @@
+package elogrusstatsd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/derWhity/elogrus"
+)
+
+// recorder is a Client remembering the metrics sent
+type recorder map[string]string
+
+func (r recorder) Gauge(name string, value float64, tags []string, rate float64) error {
+	r[name] = fmt.Sprint(value)
+	return nil
+}
+
+func (r recorder) Count(name string, value int64, tags []string, rate float64) error {
+	r[name] = fmt.Sprint(value)
+	return nil
+}
+
+func (r recorder) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	r[name] = value.String()
+	return nil
+}
+
+func TestEmitter(t *testing.T) {
+	r := recorder{}
+	e := NewEmitter(&elogrus.ElasticHook{}, r, "elogrus.")
+	e.ObserveRequest(5, time.Second, fmt.Errorf("Boom"))
+	e.emit()
+
+	expected := map[string]string{
+		"elogrus.request.latency": "1s",
+		"elogrus.request.entries": "5",
+		"elogrus.request.errors":  "1",
+		"elogrus.queue.depth":     "0",
+		"elogrus.entries.failed":  "0",
+	}
+	for name, value := range expected {
+		if r[name] != value {
+			t.Errorf("Expected %s to be %s got %q", name, value, r[name])
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package elogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Encoder serializes the documents created for the entries.
+// The configurations of jsoniter and similar packages can
+// be used directly.
+type Encoder interface {
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// EncoderFunc adapts a function to the Encoder interface
+type EncoderFunc func(v interface{}) ([]byte, error)
+
+// Marshal calls the function
+func (f EncoderFunc) Marshal(v interface{}) ([]byte, error) {
+	return f(v)
+}
+
+// WithEncoder sets the encoder serializing the documents
+// instead of encoding/json
+func WithEncoder(encoder Encoder) Option {
+	return func(hook *ElasticHook) {
+		hook.encoder = encoder
+	}
+}
+
+// encode serializes the document using the configured encoder.
+// Documents already encoded as json.RawMessage or []byte are
+// only compacted.
+func (hook *ElasticHook) encode(msg interface{}) ([]byte, error) {
+	switch body := msg.(type) {
+	case json.RawMessage:
+		return compact(body)
+	case []byte:
+		return compact(body)
+	}
+	if hook.encoder != nil {
+		body, err := hook.encoder.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		return compact(body)
+	}
+	return json.Marshal(msg)
+}
+
+// compact removes the insignificant whitespace from the document, so
+// it fits on a single line of bulk requests and dead letter files
+func compact(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err != nil {
+		return nil, fmt.Errorf("Invalid document: %v", err)
+	}
+	return buf.Bytes(), nil
+}
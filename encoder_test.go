@@ -0,0 +1,61 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithEncoder(t *testing.T) {
+	hook := &ElasticHook{}
+	WithMessageCreator(func(entry *logrus.Entry, hook *ElasticHook) interface{} {
+		return entry.Message
+	})(hook)
+	WithEncoder(EncoderFunc(func(v interface{}) ([]byte, error) {
+		return []byte(fmt.Sprintf(`{"custom":%q}`, v)), nil
+	}))(hook)
+
+	doc, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.body) != `{"custom":"Hello"}` {
+		t.Errorf("Unexpected body %s", doc.body)
+	}
+
+	WithEncoder(EncoderFunc(func(v interface{}) ([]byte, error) {
+		return nil, fmt.Errorf("Cannot encode")
+	}))(hook)
+	if _, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index"); err == nil {
+		t.Error("Expected encoder error")
+	}
+}
+
+func TestPreEncodedDocuments(t *testing.T) {
+	for _, body := range []interface{}{
+		json.RawMessage(`{"message":"Hello"}`),
+		[]byte("{\n  \"message\": \"Hello\"\n}\n"),
+	} {
+		hook := &ElasticHook{}
+		WithMessageCreator(func(entry *logrus.Entry, hook *ElasticHook) interface{} {
+			return body
+		})(hook)
+		doc, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(doc.body) != `{"message":"Hello"}` {
+			t.Errorf("Expected %T to be sent compacted, got %s", body, doc.body)
+		}
+	}
+
+	hook := &ElasticHook{}
+	WithMessageCreator(func(entry *logrus.Entry, hook *ElasticHook) interface{} {
+		return []byte(`{"message":`)
+	})(hook)
+	if _, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index"); err == nil {
+		t.Error("Expected invalid document to be rejected")
+	}
+}
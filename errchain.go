@@ -0,0 +1,89 @@
+package elogrus
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stackTraceField holds the stack trace of the error of an entry
+const stackTraceField = "error.stack_trace"
+
+// errorInfo describes an error of an error chain
+type errorInfo struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// WithErrorChain writes errors stored in the field logrus.ErrorKey as
+// an array holding the type and message of every error in the chain
+// unwrapped using errors.Unwrap, so root causes can be queried.
+// By default only the message of the error is written.
+func WithErrorChain() Option {
+	return func(hook *ElasticHook) {
+		hook.errorChain = true
+	}
+}
+
+// errorValue returns the value written for the error
+func (hook *ElasticHook) errorValue(err error) interface{} {
+	if !hook.errorChain {
+		return err.Error()
+	}
+	var chain []errorInfo
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, errorInfo{
+			Type:    fmt.Sprintf("%T", err),
+			Message: err.Error(),
+		})
+	}
+	return chain
+}
+
+// withErrorValue returns the entry with the error stored in the field
+// logrus.ErrorKey replaced by its value as written, so it is masked,
+// scrubbed and truncated like the other fields. The entry is copied
+// as it is shared with other hooks and formatters.
+func (hook *ElasticHook) withErrorValue(entry *logrus.Entry) *logrus.Entry {
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok || err == nil {
+		return entry
+	}
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	data[logrus.ErrorKey] = hook.errorValue(err)
+	copied := *entry
+	copied.Data = data
+	return &copied
+}
+
+// stackTrace returns the stack trace of the innermost error of the
+// chain providing one using a StackTrace method, like the errors
+// created by github.com/pkg/errors, or an empty string
+func stackTrace(err error) string {
+	var trace string
+	for err != nil {
+		if m := reflect.ValueOf(err).MethodByName("StackTrace"); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+			trace = strings.TrimSpace(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()))
+		}
+		if cause, ok := err.(interface{ Cause() error }); ok && errors.Unwrap(err) == nil {
+			err = cause.Cause()
+		} else {
+			err = errors.Unwrap(err)
+		}
+	}
+	return trace
+}
+
+// entryStackTrace returns the stack trace of the error of the entry
+func entryStackTrace(entry *logrus.Entry) string {
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		return stackTrace(err)
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithErrorChain(t *testing.T) {
+	hook := &ElasticHook{}
+	err := fmt.Errorf("Cannot load config: %w", &os.PathError{Op: "open", Path: "config.yml", Err: os.ErrNotExist})
+	if value := hook.errorValue(err); value != err.Error() {
+		t.Errorf("Unexpected value %v", value)
+	}
+
+	WithErrorChain()(hook)
+	msg := LogstashMessage("")(&logrus.Entry{Data: logrus.Fields{logrus.ErrorKey: err}}, hook).(logrus.Fields)
+	body, _ := json.Marshal(msg[logrus.ErrorKey])
+	expected := `[{"type":"*fmt.wrapError","message":"Cannot load config: open config.yml: file does not exist"},` +
+		`{"type":"*fs.PathError","message":"open config.yml: file does not exist"},` +
+		`{"type":"*errors.errorString","message":"file does not exist"}]`
+	if string(body) != expected {
+		t.Errorf("Expected %s got %s", expected, body)
+	}
+}
+
+// stack mimics the StackTrace type of github.com/pkg/errors
+type stack []string
+
+func (s stack) Format(f fmt.State, verb rune) {
+	for _, frame := range s {
+		fmt.Fprintf(f, "\n%s", frame)
+	}
+}
+
+type stackError struct {
+	msg   string
+	stack stack
+}
+
+func (e *stackError) Error() string     { return e.msg }
+func (e *stackError) StackTrace() stack { return e.stack }
+
+func TestStackTrace(t *testing.T) {
+	if trace := stackTrace(fmt.Errorf("Failed")); trace != "" {
+		t.Errorf("Unexpected stack trace %s", trace)
+	}
+
+	err := fmt.Errorf("Cannot load config: %w", &stackError{"Failed", stack{"main.load", "main.main"}})
+	msg := createMessage(&logrus.Entry{Data: logrus.Fields{logrus.ErrorKey: err}}, &ElasticHook{}).(map[string]interface{})
+	if msg[stackTraceField] != "main.load\nmain.main" {
+		t.Errorf("Unexpected stack trace %q", msg[stackTraceField])
+	}
+}
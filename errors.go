@@ -0,0 +1,59 @@
+package elogrus
+
+import (
+	"fmt"
+)
+
+// IndexError is returned if the index could not be checked or created.
+// It matches ErrIndexCreateFailed using errors.Is and wraps the cause.
+type IndexError struct {
+	Index string
+	Err   error
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("Cannot create index %s: %s", e.Index, e.Err)
+}
+
+// Is makes errors.Is match ErrIndexCreateFailed
+func (e *IndexError) Is(target error) bool {
+	return target == ErrIndexCreateFailed
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
+
+// BulkError is returned if some of the entries sent together could not
+// be delivered. It matches ErrBulkPartialFailure using errors.Is.
+type BulkError struct {
+	// Total is the number of entries sent
+	Total int
+	// Errors holds the failure of every entry not delivered,
+	// which is a *BulkItemError for documents rejected by ElasticSearch
+	Errors []error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("Cannot deliver %d of %d entries: %s", len(e.Errors), e.Total, e.Errors[0])
+}
+
+// Is makes errors.Is match ErrBulkPartialFailure
+func (e *BulkError) Is(target error) bool {
+	return target == ErrBulkPartialFailure
+}
+
+// UndeliveredError is returned by Close if entries could not be
+// delivered. It matches ErrUndelivered using errors.Is.
+type UndeliveredError struct {
+	Count int
+}
+
+func (e *UndeliveredError) Error() string {
+	return fmt.Sprintf("Cannot deliver %d entries", e.Count)
+}
+
+// Is makes errors.Is match ErrUndelivered
+func (e *UndeliveredError) Is(target error) bool {
+	return target == ErrUndelivered
+}
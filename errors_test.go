@@ -0,0 +1,44 @@
+package elogrus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olivere/elastic"
+)
+
+func TestIndexError(t *testing.T) {
+	cause := &elastic.Error{Status: 503}
+	var err error = &IndexError{Index: "log", Err: cause}
+
+	if !errors.Is(err, ErrIndexCreateFailed) {
+		t.Error("Expected IndexError to match ErrIndexCreateFailed")
+	}
+	var e *elastic.Error
+	if !errors.As(err, &e) || e.Status != 503 {
+		t.Error("Expected IndexError to wrap the cause")
+	}
+	if !retriableError(err) {
+		t.Error("Expected wrapped 503 to be retriable")
+	}
+}
+
+func TestBulkError(t *testing.T) {
+	var err error = &BulkError{
+		Total:  2,
+		Errors: []error{&BulkItemError{Index: "log", Status: 400}},
+	}
+	if !errors.Is(err, ErrBulkPartialFailure) {
+		t.Error("Expected BulkError to match ErrBulkPartialFailure")
+	}
+	if errors.Is(err, ErrUndelivered) {
+		t.Error("BulkError must not match ErrUndelivered")
+	}
+}
+
+func TestUndeliveredError(t *testing.T) {
+	var err error = &UndeliveredError{Count: 3}
+	if !errors.Is(err, ErrUndelivered) {
+		t.Error("Expected UndeliveredError to match ErrUndelivered")
+	}
+}
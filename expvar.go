@@ -0,0 +1,61 @@
+package elogrus
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// expvarHooks maps the published names to the hooks. A hook created
+// with the name of a previous one replaces it, as expvar does not
+// allow removing variables.
+var (
+	expvarMu    sync.Mutex
+	expvarHooks = map[string]*ElasticHook{}
+)
+
+// WithExpvar publishes the counters of the hook as expvar variable
+// elogrus.<name>, so they are shown by the /debug/vars endpoint. The
+// hook is published once it was created successfully.
+func WithExpvar(name string) Option {
+	return func(hook *ElasticHook) {
+		hook.expvarName = name
+	}
+}
+
+// publishExpvar publishes the counters of the hook
+func (hook *ElasticHook) publishExpvar() {
+	key := "elogrus." + hook.expvarName
+
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if _, ok := expvarHooks[key]; !ok {
+		expvar.Publish(key, expvar.Func(func() interface{} {
+			expvarMu.Lock()
+			hook := expvarHooks[key]
+			expvarMu.Unlock()
+			return hook.expvarStats()
+		}))
+	}
+	expvarHooks[key] = hook
+}
+
+// expvarStats returns the stats of the hook in a JSON friendly form
+func (hook *ElasticHook) expvarStats() map[string]interface{} {
+	stats := hook.Stats()
+	vars := map[string]interface{}{
+		"submitted": stats.Submitted,
+		"sent":      stats.Sent,
+		"retried":   stats.Retried,
+		"failed":    stats.Failed,
+		"dropped":   stats.Dropped,
+		"queued":    stats.Queued,
+	}
+	if stats.LastError != nil {
+		vars["last_error"] = stats.LastError.Error()
+	}
+	if !stats.LastSuccess.IsZero() {
+		vars["last_success"] = stats.LastSuccess.Format(time.RFC3339Nano)
+	}
+	return vars
+}
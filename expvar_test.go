@@ -0,0 +1,47 @@
+package elogrus
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestExpvar(t *testing.T) {
+	server, client := newStubElastic(nil)
+	defer server.Close()
+	hook, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log", WithExpvar("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+	hook.complete(&document{}, nil)
+
+	v := expvar.Get("elogrus.test")
+	if v == nil {
+		t.Fatal("Variable elogrus.test is not published")
+	}
+	if !strings.Contains(v.String(), `"sent":1`) {
+		t.Errorf("Expected 1 sent entry got %s", v.String())
+	}
+
+	// Publishing the same name again must not panic
+	(&ElasticHook{expvarName: "test"}).publishExpvar()
+}
+
+func TestExpvarFailedHook(t *testing.T) {
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		w.WriteHeader(http.StatusBadRequest)
+		return true
+	})
+	defer server.Close()
+	if _, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log", WithExpvar("failed")); err == nil {
+		t.Fatal("Expected creating the hook to fail")
+	}
+	if v := expvar.Get("elogrus.failed"); v != nil {
+		t.Errorf("Expected the hook not to be published got %s", v.String())
+	}
+}
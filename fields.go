@@ -0,0 +1,111 @@
+package elogrus
+
+import "github.com/sirupsen/logrus"
+
+// WithStaticFields adds the fields to every document. Fields
+// of the entry with the same name take precedence.
+func WithStaticFields(fields logrus.Fields) Option {
+	return func(hook *ElasticHook) {
+		hook.staticFields = fields
+	}
+}
+
+// WithAllowedFields only writes the fields of the entries with
+// the names given. All fields are written by default.
+func WithAllowedFields(names ...string) Option {
+	return func(hook *ElasticHook) {
+		hook.allowedFields = fieldSet(names)
+	}
+}
+
+// WithDeniedFields strips the fields with the names given
+// from the entries, e.g. passwords or large payloads
+func WithDeniedFields(names ...string) Option {
+	return func(hook *ElasticHook) {
+		hook.deniedFields = fieldSet(names)
+	}
+}
+
+func fieldSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// WithFieldRenames renames the fields of the entries, e.g.
+// "trace_id" to "trace.id", to match existing mappings.
+// The allow- and denylist use the original names.
+func WithFieldRenames(renames map[string]string) Option {
+	return func(hook *ElasticHook) {
+		hook.renames = renames
+	}
+}
+
+// keepField reports whether the field passes the allow- and denylist
+func (hook *ElasticHook) keepField(name string) bool {
+	if len(hook.allowedFields) > 0 && !hook.allowedFields[name] {
+		return false
+	}
+	return !hook.deniedFields[name]
+}
+
+// entryData returns the fields written for the entry. The
+// data of the entry is copied before it is changed.
+func (hook *ElasticHook) entryData(entry *logrus.Entry) logrus.Fields {
+	data := entry.Data
+	extracted := hook.contextFields(entry)
+	if len(hook.staticFields) > 0 || len(extracted) > 0 {
+		data = make(logrus.Fields, len(hook.staticFields)+len(extracted)+len(entry.Data))
+		for k, v := range hook.staticFields {
+			data[k] = v
+		}
+		for k, v := range extracted {
+			data[k] = v
+		}
+		for k, v := range entry.Data {
+			data[k] = v
+		}
+	}
+	if _, ok := data[TagsKey]; ok && hook.tags != nil {
+		data = withoutTags(data)
+	}
+	if len(hook.allowedFields) > 0 || len(hook.deniedFields) > 0 || len(hook.renames) > 0 || hook.sanitizer != nil {
+		filtered := make(logrus.Fields, len(data))
+		for k, v := range data {
+			if !hook.keepField(k) {
+				continue
+			}
+			if name, ok := hook.renames[k]; ok {
+				k = name
+			} else if hook.sanitizer != nil {
+				k = hook.sanitizer(k)
+			}
+			filtered[k] = v
+		}
+		data = filtered
+	}
+	if hook.convertsValues() {
+		data = hook.convertFields(data)
+	}
+	if hook.flattenDepth > 0 {
+		data = flattenFields(data, hook.flattenDepth, hook.sanitizer)
+	}
+	if len(hook.redactions) > 0 {
+		data = hook.redactFields(data)
+	}
+	if len(hook.pseudonyms) > 0 {
+		data = hook.pseudonymizeFields(data)
+	}
+	if hook.scrubFields && len(hook.scrubRules) > 0 {
+		data = hook.scrubData(data)
+	}
+	if hook.detectSecrets {
+		data = hook.maskSecretFields(data)
+	}
+	if hook.fieldGuard != nil {
+		data = hook.fieldGuard.apply(hook.index(), data)
+	}
+	return data
+}
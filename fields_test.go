@@ -0,0 +1,54 @@
+package elogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithStaticFields(t *testing.T) {
+	hook := &ElasticHook{}
+	WithStaticFields(logrus.Fields{"env": "prod", "app": "test"})(hook)
+
+	entry := &logrus.Entry{Data: logrus.Fields{"app": "entry", "name": "joe"}}
+	data := hook.entryData(entry)
+	if data["env"] != "prod" || data["app"] != "entry" || data["name"] != "joe" {
+		t.Errorf("Unexpected data %v", data)
+	}
+	if _, ok := entry.Data["env"]; ok {
+		t.Error("Static fields must not be added to the entry")
+	}
+}
+
+func TestFieldFilter(t *testing.T) {
+	entry := &logrus.Entry{Data: logrus.Fields{"name": "joe", "password": "secret", "age": 42}}
+
+	hook := &ElasticHook{}
+	WithDeniedFields("password")(hook)
+	data := hook.entryData(entry)
+	if len(data) != 2 || data["password"] != nil {
+		t.Errorf("Unexpected data %v", data)
+	}
+	if entry.Data["password"] != "secret" {
+		t.Error("Fields must not be removed from the entry")
+	}
+
+	hook = &ElasticHook{}
+	WithAllowedFields("name", "password")(hook)
+	WithDeniedFields("password")(hook)
+	data = hook.entryData(entry)
+	if len(data) != 1 || data["name"] != "joe" {
+		t.Errorf("Unexpected data %v", data)
+	}
+}
+
+func TestWithFieldRenames(t *testing.T) {
+	hook := &ElasticHook{}
+	WithFieldRenames(map[string]string{"trace_id": "trace.id", "msg": "text"})(hook)
+	WithDeniedFields("msg")(hook)
+
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{"trace_id": "abc", "msg": "hello", "name": "joe"}})
+	if len(data) != 2 || data["trace.id"] != "abc" || data["name"] != "joe" {
+		t.Errorf("Unexpected data %v", data)
+	}
+}
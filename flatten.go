@@ -0,0 +1,76 @@
+package elogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithFlattening flattens maps and structs stored in the fields of the
+// entries to fields with dotted names like "http.request.method", up to
+// depth levels of nesting. Deeper values are kept as objects.
+func WithFlattening(depth int) Option {
+	return func(hook *ElasticHook) {
+		hook.flattenDepth = depth
+	}
+}
+
+// flattenFields returns the fields with nested objects flattened.
+// The names of nested fields are rewritten using the sanitizer.
+func flattenFields(data logrus.Fields, depth int, sanitizer FieldNameSanitizer) logrus.Fields {
+	flat := make(logrus.Fields, len(data))
+	for k, v := range data {
+		flatten(flat, k, v, depth, sanitizer)
+	}
+	return flat
+}
+
+func flatten(dst logrus.Fields, name string, value interface{}, depth int, sanitizer FieldNameSanitizer) {
+	if depth > 0 {
+		if object, ok := objectOf(value); ok && len(object) > 0 {
+			for k, v := range object {
+				if sanitizer != nil {
+					k = sanitizer(k)
+				}
+				flatten(dst, name+"."+k, v, depth-1, sanitizer)
+			}
+			return
+		}
+	}
+	dst[name] = value
+}
+
+// objectOf returns the value as map if it is serialized as JSON object
+func objectOf(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case logrus.Fields:
+		return v, true
+	case error, json.Marshaler:
+		return nil, false
+	}
+
+	t := reflect.TypeOf(value)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || (t.Kind() != reflect.Struct && (t.Kind() != reflect.Map || t.Key().Kind() != reflect.String)) {
+		return nil, false
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	// Numbers are kept as json.Number, so large
+	// integers like IDs do not lose precision
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var object map[string]interface{}
+	if err := dec.Decode(&object); err != nil {
+		return nil, false
+	}
+	return object, true
+}
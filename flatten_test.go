@@ -0,0 +1,54 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFlattenFields(t *testing.T) {
+	type request struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+	}
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := logrus.Fields{
+		"http": map[string]interface{}{
+			"request": &request{Method: "GET", Path: "/"},
+			"status":  200,
+		},
+		"deep": logrus.Fields{"a": logrus.Fields{"b": logrus.Fields{"c": 1}}},
+		"time": ts,
+		"name": "joe",
+	}
+
+	flat := flattenFields(data, 2, nil)
+	expected := logrus.Fields{
+		"http.request.method": "GET",
+		"http.request.path":   "/",
+		"http.status":         200,
+		"deep.a.b":            logrus.Fields{"c": 1},
+		"time":                ts,
+		"name":                "joe",
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("Expected %v got %v", expected, flat)
+	}
+}
+
+func TestFlattenFieldsLargeInteger(t *testing.T) {
+	type order struct {
+		ID int64 `json:"id"`
+	}
+	flat := flattenFields(logrus.Fields{"order": order{ID: 9007199254740993}}, 1, nil)
+	body, err := json.Marshal(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"order.id":9007199254740993}`; string(body) != expected {
+		t.Errorf("Expected %s got %s", expected, body)
+	}
+}
@@ -0,0 +1,39 @@
+package elogrus
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// goroutineField holds the ID of the goroutine firing an entry
+const goroutineField = "goroutine.id"
+
+// WithGoroutineID adds the ID of the goroutine firing an entry to the
+// documents, helping to tell apart the entries of concurrent workers.
+// Reading the ID is slow, use it for debugging only.
+func WithGoroutineID() Option {
+	return func(hook *ElasticHook) {
+		hook.goroutineID = true
+	}
+}
+
+// goroutineID returns the ID of the current goroutine
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// The stack starts with "goroutine 123 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// addGoroutineID adds the ID of the current goroutine to the message
+func (hook *ElasticHook) addGoroutineID(msg map[string]interface{}) {
+	if hook.goroutineID {
+		msg[goroutineField] = goroutineID()
+	}
+}
@@ -0,0 +1,23 @@
+package elogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithGoroutineID(t *testing.T) {
+	hook := &ElasticHook{}
+	WithGoroutineID()(hook)
+
+	ids := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ids <- createMessage(&logrus.Entry{}, hook).(map[string]interface{})[goroutineField]
+		}()
+	}
+	first, second := <-ids, <-ids
+	if first == uint64(0) || second == uint64(0) || first == second {
+		t.Errorf("Unexpected goroutine IDs %v and %v", first, second)
+	}
+}
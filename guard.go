@@ -0,0 +1,73 @@
+package elogrus
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// overflowField holds the fields exceeding the field limit
+const overflowField = "overflow"
+
+// WithFieldLimit limits the number of distinct field names written to
+// an index. Fields with further names are written as key/value pairs
+// of strings to the field overflow, so high-cardinality field names
+// cannot blow up the mapping of the index.
+func WithFieldLimit(limit int) Option {
+	return func(hook *ElasticHook) {
+		if limit > 0 {
+			hook.fieldGuard = &fieldGuard{limit: limit}
+		}
+	}
+}
+
+// overflowPair is a field exceeding the field limit
+type overflowPair struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// fieldGuard tracks the field names written to the current index
+type fieldGuard struct {
+	mu    sync.Mutex
+	limit int
+	index string
+	names map[string]bool
+}
+
+// apply moves the fields exceeding the limit to the overflow field
+func (g *fieldGuard) apply(index string, data logrus.Fields) logrus.Fields {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if index != g.index || g.names == nil {
+		g.index = index
+		g.names = make(map[string]bool, g.limit)
+	}
+
+	var overflow []overflowPair
+	for k := range data {
+		if g.names[k] {
+			continue
+		}
+		if len(g.names) < g.limit {
+			g.names[k] = true
+			continue
+		}
+		overflow = append(overflow, overflowPair{Key: k, Value: fmt.Sprint(data[k])})
+	}
+	if len(overflow) == 0 {
+		return data
+	}
+
+	guarded := make(logrus.Fields, len(data)-len(overflow)+1)
+	for k, v := range data {
+		if g.names[k] {
+			guarded[k] = v
+		}
+	}
+	sort.Slice(overflow, func(i, j int) bool { return overflow[i].Key < overflow[j].Key })
+	guarded[overflowField] = overflow
+	return guarded
+}
@@ -0,0 +1,30 @@
+package elogrus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestFieldGuard(t *testing.T) {
+	g := &fieldGuard{limit: 2}
+	g.apply("index", logrus.Fields{"a": 1, "b": 2})
+
+	data := g.apply("index", logrus.Fields{"a": 1, "c": 3, "d": "x"})
+	expected := logrus.Fields{
+		"a": 1,
+		overflowField: []overflowPair{
+			{Key: "c", Value: "3"},
+			{Key: "d", Value: "x"},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v got %v", expected, data)
+	}
+
+	data = g.apply("index2", logrus.Fields{"c": 3})
+	if !reflect.DeepEqual(data, logrus.Fields{"c": 3}) {
+		t.Errorf("Expected names to be reset for a new index got %v", data)
+	}
+}
@@ -0,0 +1,112 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HealthStatus summarizes the state of a hook
+type HealthStatus int
+
+const (
+	// Healthy means the last delivery succeeded
+	Healthy HealthStatus = iota
+	// Degraded means the most recent deliveries failed
+	Degraded
+	// CircuitOpen means the circuit breaker stops requests
+	CircuitOpen
+	// Closed means the hook was closed
+	Closed
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case CircuitOpen:
+		return "circuit_open"
+	case Closed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Health describes the state of a hook
+type Health struct {
+	Status HealthStatus
+	// ConsecutiveFailures is the number of entries which
+	// could not be delivered since the last success
+	ConsecutiveFailures int64
+	// SinceLastSuccess is the time passed since the last entry was
+	// indexed. It is zero if no entry was indexed yet.
+	SinceLastSuccess time.Duration
+	// LastError is the most recent delivery failure
+	LastError error
+}
+
+// Health returns the current state of the hook,
+// e.g. for readiness checks
+func (hook *ElasticHook) Health() Health {
+	health := Health{
+		ConsecutiveFailures: atomic.LoadInt64(&hook.consecutiveFailures),
+	}
+	hook.statsMu.Lock()
+	health.LastError = hook.lastError
+	if !hook.lastSuccess.IsZero() {
+		health.SinceLastSuccess = time.Since(hook.lastSuccess)
+	}
+	hook.statsMu.Unlock()
+
+	hook.closeMu.RLock()
+	closed := hook.closed
+	hook.closeMu.RUnlock()
+
+	switch {
+	case closed:
+		health.Status = Closed
+	case hook.breaker.current() != circuitClosed:
+		health.Status = CircuitOpen
+	case health.ConsecutiveFailures > 0:
+		health.Status = Degraded
+	default:
+		health.Status = Healthy
+	}
+	return health
+}
+
+// HealthHandler returns a handler reporting the health and the stats of
+// the hook as JSON. It responds with 503 Service Unavailable while the
+// circuit is open or after the hook was closed and with 200 OK otherwise.
+func (hook *ElasticHook) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := hook.Health()
+		stats := hook.Stats()
+
+		body := map[string]interface{}{
+			"status":               health.Status.String(),
+			"consecutive_failures": health.ConsecutiveFailures,
+			"submitted":            stats.Submitted,
+			"sent":                 stats.Sent,
+			"retried":              stats.Retried,
+			"failed":               stats.Failed,
+			"dropped":              stats.Dropped,
+			"queued":               stats.Queued,
+		}
+		if !stats.LastSuccess.IsZero() {
+			body["since_last_success"] = health.SinceLastSuccess.String()
+		}
+		if health.LastError != nil {
+			body["last_error"] = health.LastError.Error()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if health.Status == CircuitOpen || health.Status == Closed {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(body)
+	})
+}
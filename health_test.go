@@ -0,0 +1,55 @@
+package elogrus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	hook := &ElasticHook{}
+	if health := hook.Health(); health.Status != Healthy {
+		t.Errorf("Expected healthy hook got %s", health.Status)
+	}
+
+	hook.complete(&document{}, ErrQueueFull)
+	hook.complete(&document{}, ErrQueueFull)
+	health := hook.Health()
+	if health.Status != Degraded || health.ConsecutiveFailures != 2 {
+		t.Errorf("Expected degraded hook with 2 failures got %+v", health)
+	}
+
+	hook.complete(&document{}, nil)
+	if health := hook.Health(); health.Status != Healthy || health.ConsecutiveFailures != 0 {
+		t.Errorf("Expected healthy hook got %+v", health)
+	}
+
+	hook.breaker = &breaker{threshold: 1, cooldown: time.Minute}
+	hook.breaker.record(false)
+	if health := hook.Health(); health.Status != CircuitOpen {
+		t.Errorf("Expected open circuit got %s", health.Status)
+	}
+
+	hook.closed = true
+	if health := hook.Health(); health.Status != Closed {
+		t.Errorf("Expected closed hook got %s", health.Status)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	hook := &ElasticHook{}
+	rec := httptest.NewRecorder()
+	hook.HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"healthy"`) {
+		t.Errorf("Unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+
+	hook.closed = true
+	rec = httptest.NewRecorder()
+	hook.HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for closed hook got %d", rec.Code)
+	}
+}
@@ -2,8 +2,11 @@ package elogrus
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -14,6 +17,24 @@ import (
 var (
 	// ErrCannotCreateIndex Fired if the index is not created
 	ErrCannotCreateIndex = fmt.Errorf("Cannot create index")
+	// ErrIndexCreateFailed Matched by every *IndexError
+	ErrIndexCreateFailed = ErrCannotCreateIndex
+	// ErrQueueFull Fired if an entry is rejected because the queue is full
+	ErrQueueFull = fmt.Errorf("Queue is full")
+	// ErrCircuitOpen Fired if an entry is rejected because the circuit breaker is open
+	ErrCircuitOpen = fmt.Errorf("Circuit breaker is open")
+	// ErrHookClosed Fired if an entry is rejected because the hook is closed
+	ErrHookClosed = fmt.Errorf("Hook is closed")
+	// ErrRequestTimeout Fired if a request did not complete within the request timeout
+	ErrRequestTimeout = fmt.Errorf("Request timed out")
+	// ErrBulkPartialFailure Matched by every *BulkError
+	ErrBulkPartialFailure = fmt.Errorf("Some entries could not be delivered")
+	// ErrMissingBulkItem Fired if a bulk response holds no result for an entry
+	ErrMissingBulkItem = fmt.Errorf("Bulk response is missing the result of the entry")
+	// ErrUndelivered Matched by every *UndeliveredError
+	ErrUndelivered = fmt.Errorf("Entries could not be delivered")
+	// ErrSkipEntry Returned by message creators to skip an entry silently
+	ErrSkipEntry = fmt.Errorf("Entry skipped")
 )
 
 // IndexNameFunc get index name
@@ -21,6 +42,57 @@ type IndexNameFunc func() string
 
 type fireFunc func(entry *logrus.Entry, hook *ElasticHook, indexName string) error
 
+// Option configures optional behaviour of an ElasticHook
+type Option func(hook *ElasticHook)
+
+// ErrorHandler is called for entries which could not be delivered
+type ErrorHandler func(err error, entry *logrus.Entry)
+
+// SuccessHandler is called for every indexed entry with the index, the
+// ID of the created document and the time passed since it was fired
+type SuccessHandler func(index, id string, latency time.Duration)
+
+// deliveryMode defines how queued documents are sent
+type deliveryMode int
+
+const (
+	modeSync deliveryMode = iota
+	modeAsync
+	modeBulk
+)
+
+const (
+	defaultWorkers = 4
+	// flushPollInterval is the time between two checks
+	// whether all flushed documents are delivered
+	flushPollInterval = 10 * time.Millisecond
+	// fatalFlushTimeout limits how long a fatal or panic entry
+	// waits for queued entries before it is sent itself
+	fatalFlushTimeout = 5 * time.Second
+	// errorBufferSize is the number of errors
+	// kept until they are read from Errors
+	errorBufferSize = 100
+)
+
+// document is a log entry prepared for indexing
+type document struct {
+	index    string
+	body     json.RawMessage
+	priority bool
+	entry    *logrus.Entry
+	segment  *walSegment
+	created  time.Time
+	// opaqueID identifies the request sending the document
+	opaqueID string
+	// id is set once the document was indexed
+	id string
+	// status is the HTTP status returned for the document
+	status int
+	// done is called with the outcome of the delivery
+	// instead of reporting failures through the hook
+	done func(err error)
+}
+
 // ElasticHook is a logrus
 // hook for ElasticSearch
 type ElasticHook struct {
@@ -31,6 +103,148 @@ type ElasticHook struct {
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 	fireFunc  fireFunc
+	creator   MessageCreatorFuncV2
+	rewriter  DocumentRewriter
+	encoder   Encoder
+
+	mode          deliveryMode
+	workers       int
+	queueSize     int
+	queueBytes    int
+	dropPolicy    DropPolicy
+	blockTimeout  time.Duration
+	usePriority   bool
+	priority      logrus.Level
+	errorHandler  ErrorHandler
+	handlerMu     sync.RWMutex
+	dropHandler   DropHandler
+	onSuccess     SuccessHandler
+	errors        chan error
+	observerMu    sync.RWMutex
+	observers     []Observer
+	slowThreshold time.Duration
+	indexedAt     bool
+	onLifecycle   LifecycleHandler
+	opaqueID      bool
+	opaqueIDField string
+	tracer        Tracer
+	onReceipt     ReceiptHandler
+	diagnostics   *logrus.Logger
+	retry         RetryPolicy
+	timeout       time.Duration
+	breaker       *breaker
+	walDir        string
+	wal           *wal
+	deadLetters   *deadLetterFile
+	batchSize     int
+	batchBytes    int
+	sizer         *batchSizer
+	limiter       *limiter
+	flushInterval time.Duration
+	queue         *queue
+	wg            sync.WaitGroup
+
+	// closeMu guards closed against concurrent fires
+	closeMu  sync.RWMutex
+	closed   bool
+	closing  chan struct{}
+	failures int64
+	// enqueued and completed count the documents handed over
+	// to and left by the queue, so Flush knows when to return
+	enqueued  int64
+	completed int64
+	// kept counts the documents left in the write-ahead
+	// log for the next start when the hook was closed
+	kept     int64
+	flushNow chan struct{}
+	// counters and outcomes reported by Stats
+	submitted           int64
+	sent                int64
+	retried             int64
+	dropped             int64
+	consecutiveFailures int64
+	inFlight            int64
+	statsMu             sync.Mutex
+	lastError           error
+	lastSuccess         time.Time
+	recentErrors        []timedError
+
+	bufferStartup bool
+	startupRetry  RetryPolicy
+	// lazy hooks create the index before the first request
+	lazy        bool
+	initMu      sync.Mutex
+	initialized int32
+	// ready is closed once the index exists; startMu
+	// guards it against concurrent synchronous fires
+	ready   chan struct{}
+	startMu sync.Mutex
+
+	// monitorIndex receives a status document every monitorInterval
+	monitorIndex    string
+	monitorInterval time.Duration
+	// heartbeatInterval is the time between two heartbeat entries
+	heartbeatInterval time.Duration
+	// dropSummary counts the drops sent every dropSummaryInterval
+	dropSummary         *dropCounter
+	dropSummaryInterval time.Duration
+	// expvarName is the name the counters are published as
+	expvarName string
+
+	// timestampField is the name of the field holding the entry time
+	timestampField  string
+	timestampFormat string
+	timezone        *time.Location
+	lowercaseKeys   bool
+	levelFormat     LevelFormatFunc
+	severity        bool
+	fieldsKey       string
+
+	// staticFields are added to every document
+	staticFields  logrus.Fields
+	fromContext   ContextFieldExtractor
+	processors    []EntryProcessor
+	allowedFields map[string]bool
+	deniedFields  map[string]bool
+	renames       map[string]string
+	flattenDepth  int
+	sanitizer     FieldNameSanitizer
+	fieldGuard    *fieldGuard
+	flattenedData bool
+	// flatIndices are the index patterns of the template
+	// installed for flattened data, the index name if empty
+	flatIndices   []string
+	redactions    []string
+	scrubRules    []scrubRule
+	scrubFields   bool
+	pseudonyms    []string
+	pseudonymSalt []byte
+	detectSecrets bool
+	onSecret      SecretHandler
+	errorChain    bool
+	callerSkip    int
+	callerIgnore  []string
+	goroutineID   bool
+
+	// timeValues, stringerValues and errorValues convert field values
+	timeValues      bool
+	valueTimeFormat string
+	stringerValues  bool
+	errorValues     bool
+
+	// metadata holds the fields written at the top level of every document
+	metadata        map[string]interface{}
+	contextMetadata []ContextMetadataFunc
+	tags            []string
+
+	maxMessageLength int
+	maxFieldLength   int
+	maxFields        int
+
+	// hostResolver updates resolvedHost every hostRefresh
+	hostResolver HostResolverFunc
+	hostRefresh  time.Duration
+	resolvedHost atomic.Value
 }
 
 // NewElasticHook creates new hook
@@ -38,8 +252,8 @@ type ElasticHook struct {
 // host - host of system
 // level - log level
 // index - name of the index in ElasticSearch
-func NewElasticHook(client *elastic.Client, host string, level logrus.Level, index string) (*ElasticHook, error) {
-	return NewElasticHookWithFunc(client, host, level, func() string { return index })
+func NewElasticHook(client *elastic.Client, host string, level logrus.Level, index string, opts ...Option) (*ElasticHook, error) {
+	return NewElasticHookWithFunc(client, host, level, func() string { return index }, opts...)
 }
 
 // NewAsyncElasticHook creates new  hook with asynchronous log
@@ -47,8 +261,8 @@ func NewElasticHook(client *elastic.Client, host string, level logrus.Level, ind
 // host - host of system
 // level - log level
 // index - name of the index in ElasticSearch
-func NewAsyncElasticHook(client *elastic.Client, host string, level logrus.Level, index string) (*ElasticHook, error) {
-	return NewAsyncElasticHookWithFunc(client, host, level, func() string { return index })
+func NewAsyncElasticHook(client *elastic.Client, host string, level logrus.Level, index string, opts ...Option) (*ElasticHook, error) {
+	return NewAsyncElasticHookWithFunc(client, host, level, func() string { return index }, opts...)
 }
 
 // NewElasticHookWithFunc creates new hook with
@@ -58,8 +272,8 @@ func NewAsyncElasticHook(client *elastic.Client, host string, level logrus.Level
 // host - host of system
 // level - log level
 // indexFunc - function providing the name of index
-func NewElasticHookWithFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc) (*ElasticHook, error) {
-	return newHookFuncAndFireFunc(client, host, level, indexFunc, syncFireFunc)
+func NewElasticHookWithFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc, opts ...Option) (*ElasticHook, error) {
+	return newHookFuncAndFireFunc(client, host, level, indexFunc, syncFireFunc, opts...)
 }
 
 // NewAsyncElasticHookWithFunc creates new asynchronous hook with
@@ -69,11 +283,104 @@ func NewElasticHookWithFunc(client *elastic.Client, host string, level logrus.Le
 // host - host of system
 // level - log level
 // indexFunc - function providing the name of index
-func NewAsyncElasticHookWithFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc) (*ElasticHook, error) {
-	return newHookFuncAndFireFunc(client, host, level, indexFunc, asyncFireFunc)
+func NewAsyncElasticHookWithFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc, opts ...Option) (*ElasticHook, error) {
+	return newHookFuncAndFireFunc(client, host, level, indexFunc, asyncFireFunc, append([]Option{asyncMode}, opts...)...)
+}
+
+// WithWorkers sets the number of goroutines
+// sending entries of an asynchronous hook
+func WithWorkers(workers int) Option {
+	return func(hook *ElasticHook) {
+		if workers > 0 {
+			hook.workers = workers
+		}
+	}
+}
+
+// WithQueueSize sets the maximum number of entries waiting to be sent
+// by an asynchronous hook. Entries fired while the queue is full are
+// rejected with ErrQueueFull.
+func WithQueueSize(size int) Option {
+	return func(hook *ElasticHook) {
+		if size > 0 {
+			hook.queueSize = size
+		}
+	}
+}
+
+// WithQueueBytes limits the total size of the serialized entries waiting
+// to be sent by an asynchronous hook, in addition to WithQueueSize
+func WithQueueBytes(size int) Option {
+	return func(hook *ElasticHook) {
+		if size > 0 {
+			hook.queueBytes = size
+		}
+	}
+}
+
+// WithDropPolicy sets which entry is discarded when
+// the queue of an asynchronous hook is full
+func WithDropPolicy(policy DropPolicy) Option {
+	return func(hook *ElasticHook) {
+		hook.dropPolicy = policy
+	}
+}
+
+// WithBlockTimeout limits how long Fire waits for free space in the
+// queue when using the Block drop policy. A zero timeout waits until
+// space is available or the hook is cancelled.
+func WithBlockTimeout(timeout time.Duration) Option {
+	return func(hook *ElasticHook) {
+		hook.blockTimeout = timeout
+	}
+}
+
+// WithRequestTimeout limits how long a single request to ElasticSearch
+// may take. Requests exceeding it fail with ErrRequestTimeout and are
+// repeated according to the retry policy. A zero timeout disables the
+// limit.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(hook *ElasticHook) {
+		hook.timeout = timeout
+	}
+}
+
+// WithPriorityLevel makes entries of the given level and above bypass
+// lower level entries in the queue of an asynchronous hook. If the queue
+// is full, they evict the oldest lower level entry.
+func WithPriorityLevel(level logrus.Level) Option {
+	return func(hook *ElasticHook) {
+		hook.usePriority = true
+		hook.priority = level
+	}
+}
+
+// WithErrorHandler sets a function called
+// for every entry which could not be delivered
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(hook *ElasticHook) {
+		hook.errorHandler = handler
+	}
 }
 
-func newHookFuncAndFireFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc, fireFunc fireFunc) (*ElasticHook, error) {
+// WithSuccessHandler sets a function called
+// for every entry which was indexed
+func WithSuccessHandler(handler SuccessHandler) Option {
+	return func(hook *ElasticHook) {
+		hook.onSuccess = handler
+	}
+}
+
+// SetErrorHandler replaces the function called for every
+// entry which could not be delivered. A nil handler
+// disables the notification.
+func (hook *ElasticHook) SetErrorHandler(handler ErrorHandler) {
+	hook.handlerMu.Lock()
+	hook.errorHandler = handler
+	hook.handlerMu.Unlock()
+}
+
+func newHookFuncAndFireFunc(client *elastic.Client, host string, level logrus.Level, indexFunc IndexNameFunc, fireFunc fireFunc, opts ...Option) (*ElasticHook, error) {
 	levels := []logrus.Level{}
 	for _, l := range []logrus.Level{
 		logrus.PanicLevel,
@@ -90,78 +397,537 @@ func newHookFuncAndFireFunc(client *elastic.Client, host string, level logrus.Le
 
 	ctx, cancel := context.WithCancel(context.TODO())
 
+	hook := &ElasticHook{
+		client:    client,
+		host:      host,
+		index:     indexFunc,
+		levels:    levels,
+		ctx:       ctx,
+		ctxCancel: cancel,
+		fireFunc:  fireFunc,
+		workers:   defaultWorkers,
+		queueSize: defaultQueueSize,
+		batchSize: defaultBatchSize,
+		retry:     defaultRetryPolicy,
+		closing:   make(chan struct{}),
+		flushNow:  make(chan struct{}, 1),
+		errors:    make(chan error, errorBufferSize),
+	}
+	for _, opt := range opts {
+		opt(hook)
+	}
+
+	ready := true
+	if !hook.lazy {
+		if err := hook.bootstrapIndex(indexFunc()); err != nil {
+			if !hook.bufferStartup || !retriableError(err) {
+				cancel()
+				return nil, err
+			}
+			ready = false
+		}
+		hook.initialized = 1
+	}
+
+	if hook.walDir != "" && hook.mode != modeSync {
+		var err error
+		if hook.wal, err = openWAL(hook.walDir); err != nil {
+			cancel()
+			return nil, err
+		}
+		// Entries left by the previous run are flushed as well
+		hook.enqueued = int64(hook.wal.replayed)
+	}
+
+	hook.start(ready)
+	if hook.expvarName != "" {
+		hook.publishExpvar()
+	}
+	return hook, nil
+}
+
+// ensureIndex creates the index if it does not exist yet
+func (hook *ElasticHook) ensureIndex(index string) error {
 	// Use the IndexExists service to check if a specified index exists.
-	exists, err := client.IndexExists(indexFunc()).Do(ctx)
+	exists, err := hook.client.IndexExists(index).Do(hook.ctx)
 	if err != nil {
 		// Handle error
-		cancel()
-		return nil, err
+		return &IndexError{Index: index, Err: err}
+	}
+	if hook.flattenedData {
+		if err := hook.putTemplate(index); err != nil {
+			return &IndexError{Index: index, Err: err}
+		}
 	}
 	if !exists {
-		createIndex, err := client.CreateIndex(indexFunc()).Do(ctx)
+		createIndex, err := hook.client.CreateIndex(index).Do(hook.ctx)
 		if err != nil {
-			cancel()
-			return nil, err
+			return &IndexError{Index: index, Err: err}
 		}
 		if !createIndex.Acknowledged {
-			cancel()
-			return nil, ErrCannotCreateIndex
+			return &IndexError{Index: index, Err: ErrCannotCreateIndex}
 		}
 	}
+	return nil
+}
 
-	return &ElasticHook{
-		client:    client,
-		host:      host,
-		index:     indexFunc,
-		levels:    levels,
-		ctx:       ctx,
-		ctxCancel: cancel,
-		fireFunc:  fireFunc,
-	}, nil
+// start launches the background workers needed by the hook's fire
+// function. If ElasticSearch is not ready yet, entries are buffered
+// until the index could be created.
+func (hook *ElasticHook) start(ready bool) {
+	hook.ready = make(chan struct{})
+	if ready {
+		close(hook.ready)
+		hook.transition(LifecycleStarted)
+	} else {
+		hook.transition(LifecyclePaused)
+		hook.wg.Add(1)
+		go hook.runBootstrap(hook.index())
+	}
+
+	switch hook.mode {
+	case modeSync:
+		if !ready {
+			hook.queue = newQueue(hook.queueSize, hook.queueBytes, hook.dropPolicy, hook.blockTimeout)
+		}
+	case modeAsync:
+		hook.queue = newQueue(hook.queueSize, hook.queueBytes, hook.dropPolicy, hook.blockTimeout)
+		hook.wg.Add(hook.workers)
+		for i := 0; i < hook.workers; i++ {
+			go hook.runWorker()
+		}
+	case modeBulk:
+		hook.queue = newQueue(hook.queueSize, hook.queueBytes, hook.dropPolicy, hook.blockTimeout)
+		hook.wg.Add(1)
+		go hook.runBulk()
+	}
+	if hook.wal != nil {
+		hook.wg.Add(1)
+		go hook.runWAL()
+	}
+	if hook.monitorInterval > 0 {
+		hook.wg.Add(1)
+		go hook.runMonitor()
+	}
+	if hook.heartbeatInterval > 0 {
+		hook.wg.Add(1)
+		go hook.runHeartbeat()
+	}
+	if hook.hostResolver != nil && hook.hostRefresh > 0 {
+		hook.wg.Add(1)
+		go hook.runHostRefresh()
+	}
+	if hook.dropSummary != nil {
+		hook.wg.Add(1)
+		go hook.runDropSummary()
+	}
 }
 
 // Fire is required to implement
 // Logrus hook
 func (hook *ElasticHook) Fire(entry *logrus.Entry) error {
-	return hook.fireFunc(entry, hook, hook.index())
+	if isDiagnostic(entry) {
+		return nil
+	}
+	hook.closeMu.RLock()
+	defer hook.closeMu.RUnlock()
+	if hook.closed {
+		return ErrHookClosed
+	}
+	index := hook.index()
+	if hook.tracer == nil {
+		return ignoreSkipped(hook.fireFunc(entry, hook, index))
+	}
+
+	end := hook.startSpan(entry.Context, "elogrus.fire", map[string]interface{}{
+		"index": index,
+		"level": entry.Level.String(),
+	})
+	err := ignoreSkipped(hook.fireFunc(entry, hook, index))
+	end(err)
+	return err
 }
 
-func asyncFireFunc(entry *logrus.Entry, hook *ElasticHook, indexName string) error {
-	go syncFireFunc(entry, hook, hook.index())
+// FireAsync sends the entry like Fire and returns a channel receiving
+// the outcome of its delivery once it is known. Asynchronous hooks
+// return right away, failed entries are neither passed to the error
+// handler nor stored as dead letters but reported through the channel.
+func (hook *ElasticHook) FireAsync(entry *logrus.Entry) <-chan error {
+	result := make(chan error, 1)
+	if err := hook.fireDone(entry, func(err error) { result <- err }); err != nil {
+		result <- err
+	}
+	return result
+}
+
+// fireDone sends the entry and calls done with the outcome unless an
+// error is returned
+func (hook *ElasticHook) fireDone(entry *logrus.Entry, done func(err error)) error {
+	hook.closeMu.RLock()
+	defer hook.closeMu.RUnlock()
+	if hook.closed {
+		return ErrHookClosed
+	}
+
+	doc, err := hook.newDocument(entry, hook.index())
+	if err == ErrSkipEntry {
+		done(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	doc.done = done
+	if hook.mode != modeSync {
+		return hook.enqueue(doc)
+	}
+	if buffered, err := hook.bufferUntilReady(doc); buffered {
+		return err
+	}
+	if err := hook.deliver(doc); err != nil {
+		return err
+	}
+	done(nil)
 	return nil
 }
 
+func asyncMode(hook *ElasticHook) {
+	hook.mode = modeAsync
+}
+
+// asyncFireFunc prepares the document and hands it over to the
+// background workers of the hook
+func asyncFireFunc(entry *logrus.Entry, hook *ElasticHook, indexName string) error {
+	doc, err := hook.newDocument(entry, indexName)
+	if err != nil {
+		return err
+	}
+	return hook.enqueue(doc)
+}
+
+// enqueue hands the document over to the background workers. As logrus
+// exits right after fatal and panic entries, these are sent
+// synchronously after flushing the queue. Documents with a done
+// callback bypass the write-ahead log.
+func (hook *ElasticHook) enqueue(doc *document) error {
+	if err := hook.ctx.Err(); err != nil {
+		return err
+	}
+	if doc.entry != nil && doc.entry.Level <= logrus.FatalLevel {
+		ctx, cancel := context.WithTimeout(hook.ctx, fatalFlushTimeout)
+		hook.flush(ctx)
+		cancel()
+		if err := hook.deliver(doc); err != nil {
+			return err
+		}
+		if doc.done != nil {
+			doc.done(nil)
+		}
+		return nil
+	}
+
+	var err error
+	if hook.wal != nil && doc.done == nil {
+		err = hook.wal.append(doc)
+	} else {
+		var dropped []*document
+		dropped, err = hook.queue.push(hook.ctx, doc)
+		hook.drop(dropped, DropReasonEvicted)
+		if err == ErrQueueFull {
+			hook.reportDrop(doc, DropReasonQueueFull)
+		}
+	}
+	if err == nil {
+		atomic.AddInt64(&hook.enqueued, 1)
+	}
+	return err
+}
+
+// drop completes documents evicted from the queue
+func (hook *ElasticHook) drop(docs []*document, reason DropReason) {
+	atomic.AddInt64(&hook.completed, int64(len(docs)))
+	for _, doc := range docs {
+		if doc.done != nil {
+			doc.done(ErrQueueFull)
+		}
+		hook.reportDrop(doc, reason)
+	}
+}
+
+// reportDrop passes a discarded document to the drop handler
+func (hook *ElasticHook) reportDrop(doc *document, reason DropReason) {
+	atomic.AddInt64(&hook.dropped, 1)
+	if hook.dropSummary != nil {
+		hook.dropSummary.add(doc.entry)
+	}
+	hook.diagnose(logrus.WarnLevel, logrus.Fields{"reason": reason}, "Entry dropped")
+	if hook.dropHandler != nil {
+		hook.dropHandler(doc.entry, reason)
+	}
+}
+
+// runWorker sends queued documents one by one
+// until the hook is closed and the queue is empty
+func (hook *ElasticHook) runWorker() {
+	defer hook.wg.Done()
+	if !hook.awaitReady() {
+		return
+	}
+	for {
+		select {
+		case <-hook.queue.ready:
+			hook.sendNext()
+		case <-hook.closing:
+			for hook.sendNext() {
+			}
+			return
+		case <-hook.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendNext sends the next queued document
+// and reports false if the queue was empty
+func (hook *ElasticHook) sendNext() bool {
+	doc, ok := hook.queue.tryPop()
+	if !ok {
+		return false
+	}
+	defer hook.recoverPanic(doc)
+	hook.complete(doc, hook.sendDocument(doc))
+	return true
+}
+
 func syncFireFunc(entry *logrus.Entry, hook *ElasticHook, indexName string) error {
-	level := entry.Level.String()
+	doc, err := hook.newDocument(entry, indexName)
+	if err != nil {
+		return err
+	}
+	if buffered, err := hook.bufferUntilReady(doc); buffered {
+		return err
+	}
+	return hook.deliver(doc)
+}
 
-	if e, ok := entry.Data[logrus.ErrorKey]; ok && e != nil {
-		if err, ok := e.(error); ok {
-			entry.Data[logrus.ErrorKey] = err.Error()
+// deliver sends the document and passes it to the success handler.
+// If this fails, the document is passed to the error handler and
+// stored as dead letter unless it has a done callback. The done
+// callback is not called, the error is returned instead.
+func (hook *ElasticHook) deliver(doc *document) error {
+	if err := hook.sendDocument(doc); err != nil {
+		hook.recordFailure(err)
+		if doc.done == nil {
+			hook.reportError(err, doc.entry)
+			hook.deadLetter(doc, err)
 		}
+		return err
 	}
+	hook.reportSuccess(doc)
+	return nil
+}
 
-	msg := struct {
-		Host      string
-		Timestamp string `json:"@timestamp"`
-		Message   string
-		Data      logrus.Fields
-		Level     string
-	}{
-		hook.host,
-		entry.Time.UTC().Format(time.RFC3339Nano),
-		entry.Message,
-		entry.Data,
-		strings.ToUpper(level),
+// newDocument serializes the entry for indexing. Diagnostic entries
+// and entries vetoed by an entry processor or skipped by the message
+// creator or the document rewriter return ErrSkipEntry, other errors
+// of these are passed to the error handler as well. Field values which
+// cannot be serialized are replaced by a placeholder.
+func (hook *ElasticHook) newDocument(entry *logrus.Entry, indexName string) (doc *document, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("Panic while creating message: %v", r)
+		}
+	}()
+	if isDiagnostic(entry) {
+		return nil, ErrSkipEntry
+	}
+	if entry = hook.process(entry); entry == nil {
+		return nil, ErrSkipEntry
+	}
+	creator := hook.creator
+	if creator == nil {
+		creator = MessageCreatorFunc(createMessage).v2()
+	}
+	msg, err := creator(entry, hook)
+	if err == ErrSkipEntry {
+		return nil, err
+	}
+	if err != nil {
+		hook.reportError(err, entry)
+		return nil, err
+	}
+	body, err := hook.encode(msg)
+	if err != nil {
+		body, err = hook.encodeSafe(msg, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hook.rewriter != nil {
+		if body, err = hook.rewriter(body, entry); err == ErrSkipEntry {
+			return nil, err
+		} else if err == nil {
+			body, err = compact(body)
+		}
+		if err != nil {
+			hook.reportError(err, entry)
+			return nil, err
+		}
+	}
+	doc = &document{
+		index:    indexName,
+		body:     body,
+		priority: hook.usePriority && entry.Level <= hook.priority,
+		entry:    entry,
+		created:  time.Now(),
+		opaqueID: hook.correlationID(entry),
 	}
+	atomic.AddInt64(&hook.submitted, 1)
+	return doc, nil
+}
 
-	_, err := hook.client.
-		Index().
-		Index(hook.index()).
-		Type("log").
-		BodyJson(msg).
-		Do(hook.ctx)
+// sendDocument indexes a single document, retrying
+// temporary failures according to the retry policy
+func (hook *ElasticHook) sendDocument(doc *document) error {
+	headers := hook.opaqueHeaders(doc.opaqueID)
+	res, err := hook.withRetry(func() (*elastic.Response, error) {
+		start := time.Now()
+		res, err := hook.perform(elastic.PerformRequestOptions{
+			Method:  "POST",
+			Path:    fmt.Sprintf("/%s/log", url.PathEscape(doc.index)),
+			Body:    string(hook.payload(doc)),
+			Headers: headers,
+		})
+		hook.observeRequest(1, start, err)
+		return res, err
+	})
+	if err != nil {
+		return err
+	}
+	doc.status = res.StatusCode
+	ret := new(elastic.IndexResponse)
+	if err := json.Unmarshal(res.Body, ret); err == nil {
+		doc.id = ret.Id
+	}
+	return nil
+}
 
-	return err
+// perform sends a request to ElasticSearch
+// guarded by the circuit breaker
+func (hook *ElasticHook) perform(opts elastic.PerformRequestOptions) (*elastic.Response, error) {
+	if err := hook.initIndex(); err != nil {
+		return nil, err
+	}
+	if err := hook.acquireCircuit(); err != nil {
+		return nil, err
+	}
+	ctx := hook.ctx
+	if hook.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.timeout)
+		defer cancel()
+	}
+	atomic.AddInt64(&hook.inFlight, 1)
+	res, err := hook.client.PerformRequest(ctx, opts)
+	atomic.AddInt64(&hook.inFlight, -1)
+	if err != nil && hook.ctx.Err() != nil {
+		// Requests cancelled with the hook tell nothing
+		// about the health of ElasticSearch
+		hook.breaker.release()
+		return res, err
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = ErrRequestTimeout
+	}
+	if state, changed := hook.breaker.record(err == nil || !retriableError(err)); changed {
+		if state == circuitOpen {
+			hook.diagnose(logrus.WarnLevel, logrus.Fields{"error": err}, "Circuit breaker opened")
+			hook.transition(LifecycleCircuitOpened)
+		} else {
+			hook.diagnose(logrus.InfoLevel, nil, "Circuit breaker closed")
+			hook.transition(LifecycleCircuitClosed)
+		}
+	}
+	return res, err
+}
+
+// complete is called once a queued document left the
+// pipeline, either delivered or failed with err
+func (hook *ElasticHook) complete(doc *document, err error) {
+	atomic.AddInt64(&hook.completed, 1)
+	if err != nil && doc.segment != nil && hook.ctx.Err() != nil {
+		// The hook was cancelled, the write-ahead log
+		// keeps the document for the next start
+		atomic.AddInt64(&hook.kept, 1)
+		return
+	}
+	if err != nil {
+		hook.recordFailure(err)
+	} else {
+		hook.reportSuccess(doc)
+	}
+	if hook.onReceipt != nil {
+		hook.onReceipt(Receipt{
+			Entry:  doc.entry,
+			Index:  doc.index,
+			ID:     doc.id,
+			Status: doc.status,
+			Err:    err,
+		})
+	}
+	if doc.done != nil {
+		doc.done(err)
+	} else if err != nil {
+		hook.reportError(err, doc.entry)
+		hook.deadLetter(doc, err)
+	}
+	if doc.segment != nil {
+		hook.wal.ack(doc.segment)
+	}
+}
+
+func (hook *ElasticHook) completeAll(docs []*document, err error) {
+	for _, doc := range docs {
+		hook.complete(doc, err)
+	}
+}
+
+// reportSuccess passes an indexed document to the success handler
+func (hook *ElasticHook) reportSuccess(doc *document) {
+	hook.recordSuccess()
+	if hook.onSuccess != nil {
+		hook.onSuccess(doc.index, doc.id, time.Since(doc.created))
+	}
+}
+
+// reportError passes a delivery failure to the error handler
+func (hook *ElasticHook) reportError(err error, entry *logrus.Entry) {
+	hook.handlerMu.RLock()
+	handler := hook.errorHandler
+	hook.handlerMu.RUnlock()
+	if handler != nil {
+		handler(err, entry)
+	}
+
+	select {
+	case hook.errors <- err:
+	default:
+		// Nobody reads the errors
+	}
+}
+
+// Errors returns a channel receiving delivery failures. Failures are
+// discarded while the channel is full, so reading it is optional.
+func (hook *ElasticHook) Errors() <-chan error {
+	return hook.errors
+}
+
+// recoverPanic keeps a panic while sending the documents from killing
+// the process and fails the documents instead. Must be deferred.
+func (hook *ElasticHook) recoverPanic(docs ...*document) {
+	if r := recover(); r != nil {
+		hook.completeAll(docs, fmt.Errorf("Panic while sending entries: %v", r))
+	}
 }
 
 // Levels Required for logrus hook implementation
@@ -173,3 +939,108 @@ func (hook *ElasticHook) Levels() []logrus.Level {
 func (hook *ElasticHook) Cancel() {
 	hook.ctxCancel()
 }
+
+// Flush sends all entries queued or batched by an asynchronous
+// hook and waits until they are delivered
+func (hook *ElasticHook) Flush() error {
+	return hook.FlushContext(context.Background())
+}
+
+// FlushContext sends all entries queued or batched by an asynchronous
+// hook and waits until they are delivered or ctx is done
+func (hook *ElasticHook) FlushContext(ctx context.Context) error {
+	hook.closeMu.RLock()
+	closed := hook.closed
+	hook.closeMu.RUnlock()
+	if closed {
+		return ErrHookClosed
+	}
+	start := time.Now()
+	err := hook.flush(ctx)
+	hook.observeFlush(start, err)
+	return err
+}
+
+func (hook *ElasticHook) flush(ctx context.Context) error {
+	if hook.mode == modeSync {
+		return nil
+	}
+
+	target := atomic.LoadInt64(&hook.enqueued)
+	select {
+	case hook.flushNow <- struct{}{}:
+	default:
+	}
+
+	ticker := time.NewTicker(flushPollInterval)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&hook.completed) < target {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hook.ctx.Done():
+			return hook.ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close stops accepting entries and sends all queued entries. It waits
+// for in-flight requests until ctx is done and cancels them afterwards.
+// The returned error reports how many entries were not delivered.
+func (hook *ElasticHook) Close(ctx context.Context) error {
+	// Wait for running fires, which may be blocked on a full queue
+	locked := make(chan struct{})
+	go func() {
+		hook.closeMu.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		hook.ctxCancel()
+		<-locked
+	}
+	if hook.closed {
+		hook.closeMu.Unlock()
+		return ErrHookClosed
+	}
+	hook.closed = true
+	hook.closeMu.Unlock()
+
+	failures := atomic.LoadInt64(&hook.failures)
+	close(hook.closing)
+	hook.transition(LifecycleDraining)
+
+	done := make(chan struct{})
+	go func() {
+		hook.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		hook.ctxCancel()
+		<-done
+	}
+	hook.ctxCancel()
+
+	// Anything still queued was abandoned because ctx is done
+	undelivered := int(atomic.LoadInt64(&hook.failures) - failures)
+	if hook.queue != nil {
+		for doc, ok := hook.queue.tryPop(); ok; doc, ok = hook.queue.tryPop() {
+			hook.complete(doc, ErrHookClosed)
+			if doc.segment == nil {
+				undelivered++
+			}
+		}
+	}
+	// Documents of the write-ahead log are counted as kept
+	undelivered += int(atomic.LoadInt64(&hook.kept))
+	hook.transition(LifecycleClosed)
+	if undelivered > 0 {
+		return &UndeliveredError{Count: undelivered}
+	}
+	return nil
+}
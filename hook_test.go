@@ -1,9 +1,15 @@
 package elogrus
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,7 +25,7 @@ import (
 
 //docker run -it --rm -p 7777:9200 -p 5601:5601 elasticsearch:alpine
 
-type NewHookFunc func(client *elastic.Client, host string, level logrus.Level, index string) (*ElasticHook, error)
+type NewHookFunc func(client *elastic.Client, host string, level logrus.Level, index string, opts ...Option) (*ElasticHook, error)
 
 type Log struct{}
 
@@ -35,6 +41,10 @@ func TestAsyncHook(t *testing.T) {
 	hookTest(NewAsyncElasticHook, "async-log", t)
 }
 
+func TestBulkHook(t *testing.T) {
+	hookTest(NewBulkElasticHook, "bulk-log", t)
+}
+
 func hookTest(hookfunc NewHookFunc, indexName string, t *testing.T) {
 	if r, err := http.Get("http://127.0.0.1:7777"); err != nil {
 		log.Fatal("Elastic not reachable")
@@ -132,3 +142,350 @@ func TestError(t *testing.T) {
 		}
 	}
 }
+
+func TestRecoverPanic(t *testing.T) {
+	hook := &ElasticHook{}
+	var delivered error
+	doc := &document{done: func(err error) { delivered = err }}
+
+	func() {
+		defer hook.recoverPanic(doc)
+		panic("boom")
+	}()
+
+	if delivered == nil {
+		t.Error("Expected the document to fail after a panic")
+	}
+	if hook.completed != 1 {
+		t.Errorf("Expected 1 completed document got %d", hook.completed)
+	}
+}
+
+func TestFireAsyncClosed(t *testing.T) {
+	hook := &ElasticHook{closed: true}
+	if err := <-hook.FireAsync(logrus.NewEntry(logrus.New())); err != ErrHookClosed {
+		t.Errorf("Expected ErrHookClosed got %v", err)
+	}
+}
+
+func TestFireAsyncFatal(t *testing.T) {
+	server, client := newStubElastic(nil)
+	defer server.Close()
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.FatalLevel
+	select {
+	case err := <-hook.FireAsync(entry):
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Outcome of the fatal entry was not reported")
+	}
+	if server.count() != 1 {
+		t.Errorf("Expected 1 indexed document got %d", server.count())
+	}
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		time.Sleep(5 * time.Millisecond)
+		return false
+	})
+	defer server.Close()
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log", WithWorkers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.InfoLevel
+		if err := hook.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hook.Close(context.TODO()); err != nil {
+		t.Errorf("Unexpected close error: %s", err)
+	}
+	if server.count() != 5 {
+		t.Errorf("Expected 5 indexed documents got %d", server.count())
+	}
+	if err := hook.Fire(logrus.NewEntry(logrus.New())); err != ErrHookClosed {
+		t.Errorf("Expected ErrHookClosed got %v", err)
+	}
+}
+
+func TestCloseDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" {
+			// Never answer before the request is cancelled
+			ioutil.ReadAll(r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	defer server.Close()
+	defer close(release)
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithWorkers(1), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.InfoLevel
+		if err := hook.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = hook.Close(ctx)
+	if e, ok := err.(*UndeliveredError); !ok || e.Count != 3 || !errors.Is(err, ErrUndelivered) {
+		t.Errorf("Expected 3 undelivered entries got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Close did not return after the deadline")
+	}
+}
+
+func TestCloseUndelivered(t *testing.T) {
+	hook := &ElasticHook{
+		ctx:       context.TODO(),
+		ctxCancel: func() {},
+		closing:   make(chan struct{}),
+	}
+	hook.start(true)
+	// Nobody sends the queued documents
+	hook.queue = newQueue(10, 0, DropNewest, 0)
+	for i := 0; i < 2; i++ {
+		hook.queue.push(context.TODO(), &document{})
+	}
+
+	err := hook.Close(context.TODO())
+	if e, ok := err.(*UndeliveredError); !ok || e.Count != 2 {
+		t.Errorf("Expected 2 undelivered entries got %v", err)
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" {
+			n := atomic.AddInt32(&inFlight, 1)
+			for max := atomic.LoadInt32(&maxInFlight); n > max; max = atomic.LoadInt32(&maxInFlight) {
+				if atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}
+		return false
+	})
+	defer server.Close()
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log", WithWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	for i := 0; i < 10; i++ {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.InfoLevel
+		if err := hook.Fire(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hook.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 10 {
+		t.Errorf("Expected 10 indexed documents got %d", server.count())
+	}
+	if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+		t.Errorf("Expected 2 requests in flight at most got %d", max)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" {
+			// Answer only after the request timed out
+			ioutil.ReadAll(r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return true
+		}
+		return false
+	})
+	defer server.Close()
+	defer close(release)
+	hook, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithRequestTimeout(20*time.Millisecond), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.InfoLevel
+	if err := hook.Fire(entry); !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("Expected ErrRequestTimeout got %v", err)
+	}
+}
+
+func TestFireBatchClosed(t *testing.T) {
+	hook := &ElasticHook{closed: true}
+	if err := hook.FireBatch([]*logrus.Entry{logrus.NewEntry(logrus.New())}); err != ErrHookClosed {
+		t.Errorf("Expected ErrHookClosed got %v", err)
+	}
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	hook := &ElasticHook{}
+	var reported error
+	hook.SetErrorHandler(func(err error, entry *logrus.Entry) {
+		reported = err
+	})
+
+	hook.reportError(ErrQueueFull, nil)
+	if reported != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull to be reported got %v", reported)
+	}
+}
+
+func TestSuccessHandler(t *testing.T) {
+	var index, id string
+	hook := &ElasticHook{
+		onSuccess: func(i, d string, latency time.Duration) {
+			index, id = i, d
+		},
+	}
+
+	hook.complete(&document{index: "log", id: "42", created: time.Now()}, nil)
+	if index != "log" || id != "42" {
+		t.Errorf("Expected document 42 in index log got %s in %s", id, index)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	hook := &ElasticHook{errors: make(chan error, 1)}
+	hook.reportError(ErrQueueFull, nil)
+	// Must not block if the channel is full
+	hook.reportError(ErrHookClosed, nil)
+
+	if err := <-hook.Errors(); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull got %v", err)
+	}
+}
+
+func TestReceiptHandler(t *testing.T) {
+	var receipts []Receipt
+	hook := &ElasticHook{}
+	WithReceiptHandler(func(receipt Receipt) {
+		receipts = append(receipts, receipt)
+	})(hook)
+
+	hook.complete(&document{index: "log", id: "1", status: 201}, nil)
+	hook.complete(&document{index: "log", status: 400}, &BulkItemError{Status: 400})
+	if len(receipts) != 2 {
+		t.Fatalf("Expected 2 receipts got %d", len(receipts))
+	}
+	if receipts[0].ID != "1" || receipts[0].Status != 201 || receipts[0].Err != nil {
+		t.Errorf("Unexpected receipt %+v", receipts[0])
+	}
+	if receipts[1].Status != 400 || receipts[1].Err == nil {
+		t.Errorf("Unexpected receipt %+v", receipts[1])
+	}
+}
+
+// stubElastic is a fake ElasticSearch server answering index checks,
+// index creation, single and bulk index requests. Requests handle
+// returns true for are answered by handle instead.
+type stubElastic struct {
+	*httptest.Server
+	handle func(w http.ResponseWriter, r *http.Request) bool
+
+	mu   sync.Mutex
+	docs []string
+}
+
+func newStubElastic(handle func(w http.ResponseWriter, r *http.Request) bool) (*stubElastic, *elastic.Client) {
+	stub := &stubElastic{handle: handle}
+	stub.Server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	client, err := elastic.NewClient(
+		elastic.SetURL(stub.URL),
+		elastic.SetHealthcheck(false),
+		elastic.SetSniff(false))
+	if err != nil {
+		log.Panic(err)
+	}
+	return stub, client
+}
+
+func (stub *stubElastic) serve(w http.ResponseWriter, r *http.Request) {
+	if stub.handle != nil && stub.handle(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == "HEAD":
+	case r.Method == "PUT":
+		fmt.Fprint(w, `{"acknowledged":true}`)
+	case r.URL.Path == "/_bulk":
+		var items []string
+		for _, doc := range readBulk(r) {
+			stub.add(doc)
+			items = append(items, `{"index":{"_id":"1","status":201}}`)
+		}
+		fmt.Fprintf(w, `{"errors":false,"items":[%s]}`, strings.Join(items, ","))
+	default:
+		body, _ := ioutil.ReadAll(r.Body)
+		stub.add(string(body))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"_id":"1","result":"created"}`)
+	}
+}
+
+// readBulk returns the documents of a bulk request
+func readBulk(r *http.Request) []string {
+	var docs []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		if scanner.Scan() {
+			docs = append(docs, scanner.Text())
+		}
+	}
+	return docs
+}
+
+func (stub *stubElastic) add(doc string) {
+	stub.mu.Lock()
+	stub.docs = append(stub.docs, doc)
+	stub.mu.Unlock()
+}
+
+// count returns the number of indexed documents
+func (stub *stubElastic) count() int {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	return len(stub.docs)
+}
@@ -0,0 +1,71 @@
+package elogrus
+
+import (
+	"os"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/sirupsen/logrus"
+)
+
+// HostResolverFunc returns the host written to the documents,
+// e.g. os.Hostname, the name of a pod or an instance ID
+type HostResolverFunc func() (string, error)
+
+// NewElasticHookWithHostname creates new hook using
+// the hostname reported by the kernel as host
+// client - ElasticSearch client using gopkg.in/olivere/elastic.v5
+// level - log level
+// index - name of the index in ElasticSearch
+func NewElasticHookWithHostname(client *elastic.Client, level logrus.Level, index string, opts ...Option) (*ElasticHook, error) {
+	return NewElasticHook(client, "", level, index, append([]Option{WithHostResolver(os.Hostname, 0)}, opts...)...)
+}
+
+// WithHostResolver sets the host using the resolver instead of the host
+// passed to the constructor. With a refresh interval greater than zero,
+// the host is resolved again periodically. If the resolver fails, the
+// previous host is kept.
+func WithHostResolver(resolver HostResolverFunc, refresh time.Duration) Option {
+	return func(hook *ElasticHook) {
+		hook.hostResolver = resolver
+		hook.hostRefresh = refresh
+		hook.resolveHost()
+	}
+}
+
+// hostname returns the host written to the documents
+func (hook *ElasticHook) hostname() string {
+	if host, ok := hook.resolvedHost.Load().(string); ok {
+		return host
+	}
+	return hook.host
+}
+
+// resolveHost updates the host using the host resolver
+func (hook *ElasticHook) resolveHost() {
+	host, err := hook.hostResolver()
+	if err != nil {
+		hook.diagnose(logrus.WarnLevel, logrus.Fields{"error": err}, "Cannot resolve host")
+		return
+	}
+	hook.resolvedHost.Store(host)
+}
+
+// runHostRefresh resolves the host every refresh
+// interval until the hook is closed
+func (hook *ElasticHook) runHostRefresh() {
+	defer hook.wg.Done()
+
+	ticker := time.NewTicker(hook.hostRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			hook.resolveHost()
+		case <-hook.closing:
+			return
+		case <-hook.ctx.Done():
+			return
+		}
+	}
+}
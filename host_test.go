@@ -0,0 +1,38 @@
+package elogrus
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWithHostResolver(t *testing.T) {
+	hook := &ElasticHook{host: "localhost"}
+	if host := hook.hostname(); host != "localhost" {
+		t.Errorf("Unexpected host %s", host)
+	}
+
+	names := []string{"pod-1", "pod-2"}
+	var err error
+	WithHostResolver(func() (string, error) {
+		if err != nil {
+			return "", err
+		}
+		name := names[0]
+		names = names[1:]
+		return name, nil
+	}, 0)(hook)
+	if host := hook.hostname(); host != "pod-1" {
+		t.Errorf("Unexpected host %s", host)
+	}
+
+	hook.resolveHost()
+	if host := hook.hostname(); host != "pod-2" {
+		t.Errorf("Unexpected host %s", host)
+	}
+
+	err = fmt.Errorf("Failed")
+	hook.resolveHost()
+	if host := hook.hostname(); host != "pod-2" {
+		t.Errorf("Expected host to be kept got %s", host)
+	}
+}
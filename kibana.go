@@ -0,0 +1,77 @@
+package elogrus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// KibanaConfig describes how to reach Kibana
+type KibanaConfig struct {
+	// URL is the base URL of Kibana, e.g. http://localhost:5601
+	URL      string
+	Username string
+	Password string
+	// Client is used for the requests, http.DefaultClient if nil
+	Client *http.Client
+	// TimeField is the time field of the data view, @timestamp if empty
+	TimeField string
+}
+
+// CreateKibanaDataView creates a Kibana data view (index pattern) for
+// the indices matching pattern, e.g. "mylog-*", using the configured
+// time field, so the entries can be explored right away. A data view
+// which already exists is left unchanged.
+func CreateKibanaDataView(ctx context.Context, kibana KibanaConfig, pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("Index pattern is empty")
+	}
+	timeField := kibana.TimeField
+	if timeField == "" {
+		timeField = defaultTimestampField
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"attributes": map[string]string{
+			"title":         pattern,
+			"timeFieldName": timeField,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The saved objects API is supported by all Kibana versions since 6.x.
+	// The pattern serves as ID, so creating the view twice conflicts.
+	endpoint := strings.TrimRight(kibana.URL, "/") + "/api/saved_objects/index-pattern/" + url.PathEscape(pattern)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("kbn-xsrf", "true")
+	if kibana.Username != "" {
+		req.SetBasicAuth(kibana.Username, kibana.Password)
+	}
+
+	client := kibana.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict || (res.StatusCode >= 200 && res.StatusCode <= 299) {
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(res.Body)
+	return fmt.Errorf("Kibana responded with status %d: %s", res.StatusCode, msg)
+}
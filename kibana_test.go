@@ -0,0 +1,43 @@
+package elogrus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateKibanaDataView(t *testing.T) {
+	created := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("kbn-xsrf") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			Attributes map[string]string
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Attributes["timeFieldName"] != "@timestamp" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if created[r.URL.Path] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		created[r.URL.Path] = true
+	}))
+	defer server.Close()
+
+	kibana := KibanaConfig{URL: server.URL}
+	for i := 0; i < 2; i++ {
+		if err := CreateKibanaDataView(context.TODO(), kibana, "mylog-*"); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	}
+	if !created["/api/saved_objects/index-pattern/mylog-*"] {
+		t.Errorf("Data view was not created: %v", created)
+	}
+}
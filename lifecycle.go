@@ -0,0 +1,57 @@
+package elogrus
+
+// LifecycleEvent is a state transition of a hook
+type LifecycleEvent int
+
+const (
+	// LifecycleStarted means the hook sends entries
+	LifecycleStarted LifecycleEvent = iota
+	// LifecyclePaused means the hook buffers entries
+	// until ElasticSearch is reachable
+	LifecyclePaused
+	// LifecycleCircuitOpened means the circuit breaker stops requests
+	LifecycleCircuitOpened
+	// LifecycleCircuitClosed means the circuit breaker lets requests pass again
+	LifecycleCircuitClosed
+	// LifecycleDraining means Close was called and
+	// the remaining entries are being sent
+	LifecycleDraining
+	// LifecycleClosed means the hook stopped
+	LifecycleClosed
+)
+
+func (e LifecycleEvent) String() string {
+	switch e {
+	case LifecycleStarted:
+		return "started"
+	case LifecyclePaused:
+		return "paused"
+	case LifecycleCircuitOpened:
+		return "circuit_opened"
+	case LifecycleCircuitClosed:
+		return "circuit_closed"
+	case LifecycleDraining:
+		return "draining"
+	case LifecycleClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// LifecycleHandler is called for every state transition of a hook
+type LifecycleHandler func(event LifecycleEvent)
+
+// WithLifecycleHandler sets a function called synchronously for
+// every state transition of the hook
+func WithLifecycleHandler(handler LifecycleHandler) Option {
+	return func(hook *ElasticHook) {
+		hook.onLifecycle = handler
+	}
+}
+
+// transition passes a state transition to the lifecycle handler
+func (hook *ElasticHook) transition(event LifecycleEvent) {
+	if hook.onLifecycle != nil {
+		hook.onLifecycle(event)
+	}
+}
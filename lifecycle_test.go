@@ -0,0 +1,33 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLifecycle(t *testing.T) {
+	var events []LifecycleEvent
+	hook := &ElasticHook{
+		ctx:       context.TODO(),
+		ctxCancel: func() {},
+		closing:   make(chan struct{}),
+	}
+	WithLifecycleHandler(func(event LifecycleEvent) {
+		events = append(events, event)
+	})(hook)
+
+	hook.start(true)
+	if err := hook.Close(context.TODO()); err != nil {
+		t.Fatalf("Unexpected close error: %s", err)
+	}
+
+	expected := []LifecycleEvent{LifecycleStarted, LifecycleDraining, LifecycleClosed}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v got %v", expected, events)
+	}
+	for i, event := range expected {
+		if events[i] != event {
+			t.Errorf("Expected %s got %s", event, events[i])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package elogrus
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// truncationMarker is appended to truncated strings
+const truncationMarker = "…"
+
+// WithMaxMessageLength truncates messages longer than
+// the number of bytes given
+func WithMaxMessageLength(length int) Option {
+	return func(hook *ElasticHook) {
+		hook.maxMessageLength = length
+	}
+}
+
+// WithMaxFieldLength truncates string field values
+// longer than the number of bytes given
+func WithMaxFieldLength(length int) Option {
+	return func(hook *ElasticHook) {
+		hook.maxFieldLength = length
+	}
+}
+
+// WithMaxFields limits the number of fields written per document,
+// keeping the fields with the lowest names. The number of fields
+// omitted is written to the field OmittedFields.
+func WithMaxFields(count int) Option {
+	return func(hook *ElasticHook) {
+		hook.maxFields = count
+	}
+}
+
+// limits describes how a document was cut down to the limits
+type limits struct {
+	truncated bool
+	omitted   int
+}
+
+// truncate shortens s to at most max bytes plus the
+// truncation marker. A max of zero disables the limit.
+func truncate(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + truncationMarker, true
+}
+
+// limitMessage truncates the message of the entry
+func (hook *ElasticHook) limitMessage(message string, l *limits) string {
+	message, truncated := truncate(message, hook.maxMessageLength)
+	l.truncated = l.truncated || truncated
+	return message
+}
+
+// limitData drops the fields exceeding the maximum
+// number of fields and truncates the string values
+func (hook *ElasticHook) limitData(data logrus.Fields, l *limits) logrus.Fields {
+	if hook.maxFields > 0 && len(data) > hook.maxFields {
+		names := make([]string, 0, len(data))
+		for k := range data {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		kept := make(logrus.Fields, hook.maxFields)
+		for _, k := range names[:hook.maxFields] {
+			kept[k] = data[k]
+		}
+		l.omitted = len(data) - hook.maxFields
+		data = kept
+	}
+	if hook.maxFieldLength <= 0 {
+		return data
+	}
+	limited := make(logrus.Fields, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			var truncated bool
+			v, truncated = truncate(s, hook.maxFieldLength)
+			l.truncated = l.truncated || truncated
+		}
+		limited[k] = v
+	}
+	return limited
+}
+
+// mark adds the fields describing the applied limits to the message
+func (l limits) mark(msg map[string]interface{}, lowercase bool) {
+	truncated, omitted := "Truncated", "OmittedFields"
+	if lowercase {
+		truncated, omitted = "truncated", "omitted_fields"
+	}
+	if l.truncated {
+		msg[truncated] = true
+	}
+	if l.omitted > 0 {
+		msg[omitted] = l.omitted
+	}
+}
@@ -0,0 +1,64 @@
+package elogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		s, expected string
+		max         int
+	}{
+		{"hello", "hello", 0},
+		{"hello", "hello", 5},
+		{"hello", "hel…", 3},
+		{"größe", "gr…", 3},
+	} {
+		if got, _ := truncate(test.s, test.max); got != test.expected {
+			t.Errorf("Expected %s for %s got %s", test.expected, test.s, got)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	hook := &ElasticHook{}
+	WithMaxMessageLength(5)(hook)
+	WithMaxFieldLength(3)(hook)
+
+	msg := createMessage(&logrus.Entry{
+		Message: "Hello world",
+		Data:    logrus.Fields{"name": "joe", "payload": "large", "size": 12345},
+	}, hook).(map[string]interface{})
+	if msg["Message"] != "Hello…" {
+		t.Errorf("Unexpected message %v", msg["Message"])
+	}
+	data := msg["Data"].(logrus.Fields)
+	if data["name"] != "joe" || data["payload"] != "lar…" || data["size"] != 12345 {
+		t.Errorf("Unexpected data %v", data)
+	}
+	if msg["Truncated"] != true {
+		t.Error("Expected document to be marked as truncated")
+	}
+
+	msg = createMessage(&logrus.Entry{Message: "Hello"}, hook).(map[string]interface{})
+	if _, ok := msg["Truncated"]; ok {
+		t.Error("Unexpected truncated flag")
+	}
+}
+
+func TestWithMaxFields(t *testing.T) {
+	hook := &ElasticHook{}
+	WithMaxFields(2)(hook)
+	WithLowercaseKeys()(hook)
+
+	msg := createMessage(&logrus.Entry{Data: logrus.Fields{"c": 3, "a": 1, "b": 2, "d": 4}}, hook).(map[string]interface{})
+	data := msg["data"].(logrus.Fields)
+	if len(data) != 2 || data["a"] != 1 || data["b"] != 2 {
+		t.Errorf("Unexpected data %v", data)
+	}
+	if msg["omitted_fields"] != 2 {
+		t.Errorf("Unexpected omitted fields %v", msg["omitted_fields"])
+	}
+}
@@ -0,0 +1,63 @@
+package elogrus
+
+import (
+	"strings"
+)
+
+// WithFlattenedData maps the object holding the fields of the entries as
+// "flattened" field. The fields can no longer be queried as separate
+// fields but cannot blow up the mapping either. The mapping is installed
+// as index template for the index patterns, like "mylog-*" for indices
+// named by an IndexNameFunc, or for the index name if none are given, so
+// it applies to every index created later on. Requires ElasticSearch 7.3
+// or a later 7.x release and the default message creator: older releases
+// do not know the type and ElasticSearch 8 rejects the typed mapping.
+func WithFlattenedData(patterns ...string) Option {
+	return func(hook *ElasticHook) {
+		hook.flattenedData = true
+		hook.flatIndices = patterns
+	}
+}
+
+// putTemplate installs the index template mapping the fields as
+// flattened field for the index patterns or the index
+func (hook *ElasticHook) putTemplate(index string) error {
+	patterns := hook.flatIndices
+	if len(patterns) == 0 {
+		patterns = []string{index}
+	}
+	res, err := hook.client.IndexPutTemplate(templateName(patterns)).
+		// ElasticSearch 7 rejects typed mappings by default
+		IncludeTypeName(true).
+		BodyJson(hook.templateBody(patterns)).
+		Do(hook.ctx)
+	if err != nil {
+		return err
+	}
+	if !res.Acknowledged {
+		return ErrCannotCreateIndex
+	}
+	return nil
+}
+
+// templateName returns the name of the index template for the patterns
+func templateName(patterns []string) string {
+	name := strings.NewReplacer("*", "", ",", "-").Replace(strings.Join(patterns, ","))
+	return "elogrus-" + strings.Trim(name, "-_.")
+}
+
+// templateBody returns the body of the index template. The mappings
+// are given for the type "log" the documents are indexed as, so the
+// template must be installed with include_type_name set.
+func (hook *ElasticHook) templateBody(patterns []string) interface{} {
+	return map[string]interface{}{
+		"index_patterns": patterns,
+		"mappings": map[string]interface{}{
+			"log": map[string]interface{}{
+				"properties": map[string]interface{}{
+					hook.dataKey(): map[string]string{"type": "flattened"},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package elogrus
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTemplateBody(t *testing.T) {
+	hook := &ElasticHook{}
+	WithFlattenedData()(hook)
+	WithLowercaseKeys()(hook)
+	body, err := json.Marshal(hook.templateBody([]string{"mylog-*"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"index_patterns":["mylog-*"],"mappings":{"log":{"properties":{"data":{"type":"flattened"}}}}}`
+	if string(body) != expected {
+		t.Errorf("Expected %s got %s", expected, body)
+	}
+	if name := templateName([]string{"mylog-*"}); name != "elogrus-mylog" {
+		t.Errorf("Unexpected template name %s", name)
+	}
+}
+
+func TestFlattenedDataTemplate(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]string{}
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		switch r.Method {
+		case "HEAD":
+			w.WriteHeader(http.StatusNotFound)
+			return true
+		case "PUT":
+			buf, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			requests[r.URL.Path+"?"+r.URL.RawQuery] = string(buf)
+			mu.Unlock()
+		}
+		return false
+	})
+	defer server.Close()
+
+	hook, err := NewElasticHookWithFunc(client, "localhost", logrus.DebugLevel,
+		func() string { return "mylog-2018.01.02" }, WithFlattenedData("mylog-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := `{"index_patterns":["mylog-*"],"mappings":{"log":{"properties":{"Data":{"type":"flattened"}}}}}`
+	if body := requests["/_template/elogrus-mylog?include_type_name=true"]; body != expected {
+		t.Errorf("Expected template %s got %v", expected, requests)
+	}
+	// The index itself is created without a mapping of its own,
+	// so later indices get the same mapping from the template
+	if body, ok := requests["/mylog-2018.01.02?"]; !ok || body != "" {
+		t.Errorf("Expected index to be created without body got %v", requests)
+	}
+}
@@ -0,0 +1,226 @@
+package elogrus
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultTimestampField = "@timestamp"
+
+// MessageCreatorFunc creates the document indexed for an entry.
+// The returned value is serialized using encoding/json unless it
+// is a json.RawMessage or []byte, which is only validated and
+// compacted.
+type MessageCreatorFunc func(entry *logrus.Entry, hook *ElasticHook) interface{}
+
+// MessageCreatorFuncV2 creates the document indexed for an entry like
+// MessageCreatorFunc. Returning ErrSkipEntry skips the entry silently,
+// other errors are passed to the error handler and the entry is not sent.
+type MessageCreatorFuncV2 func(entry *logrus.Entry, hook *ElasticHook) (interface{}, error)
+
+// WithMessageCreator sets the function creating the documents
+// indexed for the entries
+func WithMessageCreator(creator MessageCreatorFunc) Option {
+	return func(hook *ElasticHook) {
+		if creator != nil {
+			hook.creator = creator.v2()
+		}
+	}
+}
+
+// v2 returns the creator as MessageCreatorFuncV2
+func (creator MessageCreatorFunc) v2() MessageCreatorFuncV2 {
+	return func(entry *logrus.Entry, hook *ElasticHook) (interface{}, error) {
+		return creator(entry, hook), nil
+	}
+}
+
+// WithMessageCreatorV2 sets the function creating the documents
+// indexed for the entries, which may skip or reject entries
+func WithMessageCreatorV2(creator MessageCreatorFuncV2) Option {
+	return func(hook *ElasticHook) {
+		if creator != nil {
+			hook.creator = creator
+		}
+	}
+}
+
+// ignoreSkipped returns nil for the error of a skipped entry
+func ignoreSkipped(err error) error {
+	if err == ErrSkipEntry {
+		return nil
+	}
+	return err
+}
+
+// LevelFormatFunc returns the string written for the level of an entry
+type LevelFormatFunc func(level logrus.Level) string
+
+// WithLevelFormat sets the function returning the string written for
+// the level of an entry, e.g. "WARNING" instead of "WARN". The levels
+// are written in upper case by default.
+func WithLevelFormat(format LevelFormatFunc) Option {
+	return func(hook *ElasticHook) {
+		hook.levelFormat = format
+	}
+}
+
+// formatLevel returns the string written for the level
+func (hook *ElasticHook) formatLevel(level logrus.Level) string {
+	if hook.levelFormat != nil {
+		return hook.levelFormat(level)
+	}
+	return strings.ToUpper(level.String())
+}
+
+// Severity returns the RFC 5424 severity of the level, ranging
+// from 0 (emergency) to 7 (debug)
+func Severity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 0
+	case logrus.FatalLevel:
+		return 2
+	case logrus.ErrorLevel:
+		return 3
+	case logrus.WarnLevel:
+		return 4
+	case logrus.InfoLevel:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// WithSeverity adds the field severity holding the numeric
+// RFC 5424 severity of the entry next to its level
+func WithSeverity() Option {
+	return func(hook *ElasticHook) {
+		hook.severity = true
+	}
+}
+
+// WithTimestampField sets the name of the field holding the
+// time of the entry, "@timestamp" by default
+func WithTimestampField(name string) Option {
+	return func(hook *ElasticHook) {
+		hook.timestampField = name
+	}
+}
+
+// timestampKey returns the name of the field holding the time of the entry
+func (hook *ElasticHook) timestampKey() string {
+	if hook.timestampField == "" {
+		return defaultTimestampField
+	}
+	return hook.timestampField
+}
+
+// LogstashMessage returns a message creator producing documents in the
+// format written by Logstash, holding the fields @version, @timestamp,
+// host, message and level. The fields of the entry are stored in an
+// object named root or at the top level if root is empty.
+func LogstashMessage(root string) MessageCreatorFunc {
+	return func(entry *logrus.Entry, hook *ElasticHook) interface{} {
+		msg := logrus.Fields{}
+		fields := msg
+		if root != "" {
+			fields = logrus.Fields{}
+			msg[root] = fields
+		}
+		trace := entryStackTrace(entry)
+		entry = hook.withErrorValue(entry)
+		var l limits
+		for k, v := range hook.limitData(hook.entryData(entry), &l) {
+			fields[k] = v
+		}
+
+		if trace != "" {
+			msg[stackTraceField] = trace
+		}
+		hook.addCaller(msg, entry)
+		hook.addGoroutineID(msg)
+		hook.addMetadata(msg, entry)
+		hook.addTags(msg, entry)
+		msg["@version"] = "1"
+		msg[hook.timestampKey()] = hook.formatTime(entry.Time)
+		msg["host"] = hook.hostname()
+		msg["message"] = hook.message(entry, &l)
+		msg["level"] = entry.Level.String()
+		if hook.levelFormat != nil {
+			msg["level"] = hook.levelFormat(entry.Level)
+		}
+		if hook.severity {
+			msg["severity"] = Severity(entry.Level)
+		}
+		l.mark(msg, true)
+		return msg
+	}
+}
+
+// message returns the message written for the entry
+func (hook *ElasticHook) message(entry *logrus.Entry, l *limits) string {
+	message := hook.maskSecrets("", hook.scrub(entry.Message))
+	return hook.limitMessage(message, l)
+}
+
+// WithLowercaseKeys makes the default message creator write the
+// fields in lower case, e.g. host, message, data and level
+func WithLowercaseKeys() Option {
+	return func(hook *ElasticHook) {
+		hook.lowercaseKeys = true
+	}
+}
+
+// WithFieldsKey sets the name of the object the default message
+// creator nests the fields of the entries under, e.g. "fields" or
+// "labels", instead of Data
+func WithFieldsKey(name string) Option {
+	return func(hook *ElasticHook) {
+		hook.fieldsKey = name
+	}
+}
+
+// dataKey returns the name of the object the default
+// message creator nests the fields of the entries under
+func (hook *ElasticHook) dataKey() string {
+	switch {
+	case hook.fieldsKey != "":
+		return hook.fieldsKey
+	case hook.lowercaseKeys:
+		return "data"
+	default:
+		return "Data"
+	}
+}
+
+func createMessage(entry *logrus.Entry, hook *ElasticHook) interface{} {
+	trace := entryStackTrace(entry)
+	entry = hook.withErrorValue(entry)
+
+	host, message, levelKey, severity := "Host", "Message", "Level", "Severity"
+	if hook.lowercaseKeys {
+		host, message, levelKey, severity = "host", "message", "level", "severity"
+	}
+	var l limits
+	msg := map[string]interface{}{
+		host:                hook.hostname(),
+		hook.timestampKey(): hook.formatTime(entry.Time),
+		message:             hook.message(entry, &l),
+		hook.dataKey():      hook.limitData(hook.entryData(entry), &l),
+		levelKey:            hook.formatLevel(entry.Level),
+	}
+	if trace != "" {
+		msg[stackTraceField] = trace
+	}
+	hook.addCaller(msg, entry)
+	hook.addGoroutineID(msg)
+	hook.addMetadata(msg, entry)
+	hook.addTags(msg, entry)
+	if hook.severity {
+		msg[severity] = Severity(entry.Level)
+	}
+	l.mark(msg, hook.lowercaseKeys)
+	return msg
+}
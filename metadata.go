@@ -0,0 +1,126 @@
+package elogrus
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// processStart approximates the start time of the process
+var processStart = time.Now()
+
+// setMetadata adds a field written at the top level of every document
+func (hook *ElasticHook) setMetadata(name string, value interface{}) {
+	if hook.metadata == nil {
+		hook.metadata = map[string]interface{}{}
+	}
+	hook.metadata[name] = value
+}
+
+// ContextMetadataFunc returns fields written at the top level of
+// the document of an entry logged with a context, e.g. trace IDs
+type ContextMetadataFunc func(ctx context.Context) map[string]interface{}
+
+// WithContextMetadata adds the fields returned by the function for
+// the context of an entry to its document. Entries logged without
+// a context are not passed to the function.
+func WithContextMetadata(extract ContextMetadataFunc) Option {
+	return func(hook *ElasticHook) {
+		hook.contextMetadata = append(hook.contextMetadata, extract)
+	}
+}
+
+// addMetadata adds the metadata fields and the fields
+// taken from the context of the entry to the message
+func (hook *ElasticHook) addMetadata(msg map[string]interface{}, entry *logrus.Entry) {
+	for k, v := range hook.metadata {
+		if t, ok := v.(time.Time); ok {
+			v = hook.formatTime(t)
+		}
+		msg[k] = v
+	}
+	if entry.Context == nil {
+		return
+	}
+	for _, extract := range hook.contextMetadata {
+		for k, v := range extract(entry.Context) {
+			msg[k] = v
+		}
+	}
+}
+
+// WithProcessMetadata adds the fields process.pid, process.executable
+// and process.start_time to every document, telling apart the
+// instances running on a host. The start time is the time the
+// package was initialized.
+func WithProcessMetadata() Option {
+	return func(hook *ElasticHook) {
+		hook.setMetadata("process.pid", os.Getpid())
+		if executable, err := os.Executable(); err == nil {
+			hook.setMetadata("process.executable", executable)
+		}
+		hook.setMetadata("process.start_time", processStart)
+	}
+}
+
+// WithBuildInfo adds the version of the main module, the VCS revision
+// it was built from and the Go version to every document as
+// build.version, build.revision and build.go_version
+func WithBuildInfo() Option {
+	return func(hook *ElasticHook) {
+		hook.setMetadata("build.go_version", runtime.Version())
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		hook.setMetadata("build.version", info.Main.Version)
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				hook.setMetadata("build.revision", setting.Value)
+			}
+		}
+	}
+}
+
+// WithEnvFields adds fields holding the values of environment variables
+// to every document, e.g. map[string]string{"DEPLOY_ENV": "labels.env"}.
+// The variables are read once while creating the hook, variables
+// which are not set are skipped.
+func WithEnvFields(fields map[string]string) Option {
+	return func(hook *ElasticHook) {
+		for env, field := range fields {
+			if value, ok := os.LookupEnv(env); ok {
+				hook.setMetadata(field, value)
+			}
+		}
+	}
+}
+
+// WithServiceName writes the name of the service
+// to the field service.name of every document
+func WithServiceName(name string) Option {
+	return func(hook *ElasticHook) {
+		hook.setMetadata("service.name", name)
+	}
+}
+
+// WithServiceVersion writes the version of the service
+// to the field service.version of every document
+func WithServiceVersion(version string) Option {
+	return func(hook *ElasticHook) {
+		hook.setMetadata("service.version", version)
+	}
+}
+
+// WithServiceEnvironment writes the environment the service runs
+// in, e.g. "production", to the field service.environment of
+// every document
+func WithServiceEnvironment(environment string) Option {
+	return func(hook *ElasticHook) {
+		hook.setMetadata("service.environment", environment)
+	}
+}
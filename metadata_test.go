@@ -0,0 +1,84 @@
+package elogrus
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithProcessMetadata(t *testing.T) {
+	hook := &ElasticHook{}
+	WithProcessMetadata()(hook)
+	WithTimestampFormat(TimestampEpochSeconds)(hook)
+
+	msg := createMessage(&logrus.Entry{}, hook).(map[string]interface{})
+	if msg["process.pid"] != os.Getpid() {
+		t.Errorf("Unexpected pid %v", msg["process.pid"])
+	}
+	if executable, _ := os.Executable(); msg["process.executable"] != executable {
+		t.Errorf("Unexpected executable %v", msg["process.executable"])
+	}
+	if msg["process.start_time"] != processStart.Unix() {
+		t.Errorf("Unexpected start time %v", msg["process.start_time"])
+	}
+}
+
+func TestWithBuildInfo(t *testing.T) {
+	hook := &ElasticHook{}
+	WithBuildInfo()(hook)
+
+	msg := createMessage(&logrus.Entry{}, hook).(map[string]interface{})
+	if msg["build.go_version"] != runtime.Version() {
+		t.Errorf("Unexpected Go version %v", msg["build.go_version"])
+	}
+	if _, ok := msg["build.version"]; !ok {
+		t.Error("Expected build version")
+	}
+}
+
+func TestWithEnvFields(t *testing.T) {
+	os.Setenv("ELOGRUS_TEST_ENV", "prod")
+	defer os.Unsetenv("ELOGRUS_TEST_ENV")
+
+	hook := &ElasticHook{}
+	WithEnvFields(map[string]string{
+		"ELOGRUS_TEST_ENV":   "labels.env",
+		"ELOGRUS_TEST_UNSET": "labels.unset",
+	})(hook)
+	if len(hook.metadata) != 1 || hook.metadata["labels.env"] != "prod" {
+		t.Errorf("Unexpected metadata %v", hook.metadata)
+	}
+}
+
+func TestServiceFields(t *testing.T) {
+	hook := &ElasticHook{}
+	WithServiceName("billing")(hook)
+	WithServiceVersion("1.2.0")(hook)
+	WithServiceEnvironment("production")(hook)
+
+	msg := createMessage(&logrus.Entry{}, hook).(map[string]interface{})
+	if msg["service.name"] != "billing" || msg["service.version"] != "1.2.0" || msg["service.environment"] != "production" {
+		t.Errorf("Unexpected service fields %v", msg)
+	}
+}
+
+func TestWithContextMetadata(t *testing.T) {
+	type key struct{}
+	hook := &ElasticHook{}
+	WithContextMetadata(func(ctx context.Context) map[string]interface{} {
+		return map[string]interface{}{"request.id": ctx.Value(key{})}
+	})(hook)
+
+	msg := createMessage(&logrus.Entry{}, hook).(map[string]interface{})
+	if _, ok := msg["request.id"]; ok {
+		t.Error("Unexpected request ID without context")
+	}
+	ctx := context.WithValue(context.Background(), key{}, "abc")
+	msg = createMessage(&logrus.Entry{Context: ctx}, hook).(map[string]interface{})
+	if msg["request.id"] != "abc" {
+		t.Errorf("Unexpected request ID %v", msg["request.id"])
+	}
+}
@@ -0,0 +1,152 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/sirupsen/logrus"
+)
+
+// WithMonitorIndex makes the hook index a document describing its
+// state, like the queue depth and the error counts, into index every
+// interval, so the health of every host can be watched in ElasticSearch
+func WithMonitorIndex(index string, interval time.Duration) Option {
+	return func(hook *ElasticHook) {
+		if interval > 0 {
+			hook.monitorIndex = index
+			hook.monitorInterval = interval
+		}
+	}
+}
+
+// heartbeatField marks heartbeat entries
+const heartbeatField = "heartbeat"
+
+// WithHeartbeat makes the hook send a heartbeat entry every interval
+// along with the regular entries, so consumers can tell an application
+// producing no entries apart from a broken hook. Heartbeats are info
+// entries with the message "heartbeat" and the field heartbeat set.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(hook *ElasticHook) {
+		hook.heartbeatInterval = interval
+	}
+}
+
+// statusDocument is indexed into the monitor index
+type statusDocument struct {
+	Timestamp   string `json:"@timestamp"`
+	Host        string `json:"host"`
+	Version     string `json:"version"`
+	Index       string `json:"index"`
+	Queued      int    `json:"queued"`
+	Submitted   int64  `json:"submitted"`
+	Sent        int64  `json:"sent"`
+	Retried     int64  `json:"retried"`
+	Failed      int64  `json:"failed"`
+	Dropped     int64  `json:"dropped"`
+	LastError   string `json:"last_error,omitempty"`
+	LastSuccess string `json:"last_success,omitempty"`
+}
+
+// runMonitor indexes a status document every monitor
+// interval until the hook is closed
+func (hook *ElasticHook) runMonitor() {
+	defer hook.wg.Done()
+
+	ticker := time.NewTicker(hook.monitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := hook.sendStatus(); err != nil {
+				hook.diagnose(logrus.WarnLevel, logrus.Fields{"error": err}, "Cannot send status document")
+			}
+		case <-hook.closing:
+			return
+		case <-hook.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendStatus indexes the current state of the hook
+func (hook *ElasticHook) sendStatus() error {
+	stats := hook.Stats()
+	status := statusDocument{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Host:      hook.hostname(),
+		Version:   moduleVersion(),
+		Index:     hook.index(),
+		Queued:    stats.Queued,
+		Submitted: stats.Submitted,
+		Sent:      stats.Sent,
+		Retried:   stats.Retried,
+		Failed:    stats.Failed,
+		Dropped:   stats.Dropped,
+	}
+	if stats.LastError != nil {
+		status.LastError = stats.LastError.Error()
+	}
+	if !stats.LastSuccess.IsZero() {
+		status.LastSuccess = stats.LastSuccess.UTC().Format(time.RFC3339Nano)
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = hook.perform(elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/%s/log", url.PathEscape(hook.monitorIndex)),
+		Body:   string(body),
+	})
+	return err
+}
+
+// moduleVersion returns the version of the
+// module this package was built from
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	pkg := reflect.TypeOf(ElasticHook{}).PkgPath()
+	for _, mod := range append([]*debug.Module{&info.Main}, info.Deps...) {
+		if mod.Path != "" && (pkg == mod.Path || strings.HasPrefix(pkg, mod.Path+"/")) {
+			return mod.Version
+		}
+	}
+	return "unknown"
+}
+
+// runHeartbeat fires a heartbeat entry every
+// heartbeat interval until the hook is closed
+func (hook *ElasticHook) runHeartbeat() {
+	defer hook.wg.Done()
+
+	logger := logrus.New()
+	ticker := time.NewTicker(hook.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			entry := logrus.NewEntry(logger).WithField(heartbeatField, true)
+			entry.Time = time.Now()
+			entry.Level = logrus.InfoLevel
+			entry.Message = "heartbeat"
+			if err := hook.Fire(entry); err != nil {
+				hook.diagnose(logrus.WarnLevel, logrus.Fields{"error": err}, "Cannot send heartbeat")
+			}
+		case <-hook.closing:
+			return
+		case <-hook.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package elogrus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithMonitorIndex(t *testing.T) {
+	hook := &ElasticHook{}
+	WithMonitorIndex("elogrus-monitor", 0)(hook)
+	if hook.monitorInterval != 0 {
+		t.Error("Expected monitoring to stay disabled without interval")
+	}
+
+	WithMonitorIndex("elogrus-monitor", time.Minute)(hook)
+	if hook.monitorIndex != "elogrus-monitor" || hook.monitorInterval != time.Minute {
+		t.Errorf("Unexpected monitor settings %s %s", hook.monitorIndex, hook.monitorInterval)
+	}
+}
+
+func TestMonitorStatus(t *testing.T) {
+	statuses := make(chan string, 10)
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" && r.URL.Path == "/elogrus-monitor/log" {
+			body, _ := ioutil.ReadAll(r.Body)
+			select {
+			case statuses <- string(body):
+			default:
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"_id":"1","result":"created"}`)
+			return true
+		}
+		return false
+	})
+	defer server.Close()
+
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithMonitorIndex("elogrus-monitor", 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+	if err := hook.Fire(infoEntry()); err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for a status reporting the delivered entry
+	timeout := time.After(time.Second)
+	var status statusDocument
+	for status.Sent != 1 {
+		select {
+		case body := <-statuses:
+			if err := json.Unmarshal([]byte(body), &status); err != nil {
+				t.Fatalf("Invalid status document %s", body)
+			}
+		case <-timeout:
+			t.Fatalf("Expected a status document reporting the sent entry got %+v", status)
+		}
+	}
+	if status.Host != "localhost" || status.Index != "log" || status.Submitted != 1 ||
+		status.Failed != 0 || status.Version != moduleVersion() || status.Version == "" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, status.Timestamp); err != nil {
+		t.Errorf("Unexpected timestamp %q", status.Timestamp)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, status.LastSuccess); err != nil {
+		t.Errorf("Unexpected time of the last success %q", status.LastSuccess)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	fired := make(chan *logrus.Entry, 1)
+	hook := &ElasticHook{
+		index:             func() string { return "log" },
+		closing:           make(chan struct{}),
+		ctx:               context.TODO(),
+		heartbeatInterval: time.Millisecond,
+		fireFunc: func(entry *logrus.Entry, hook *ElasticHook, indexName string) error {
+			select {
+			case fired <- entry:
+			default:
+			}
+			return nil
+		},
+	}
+	hook.wg.Add(1)
+	go hook.runHeartbeat()
+
+	entry := <-fired
+	close(hook.closing)
+	hook.wg.Wait()
+	if entry.Message != "heartbeat" || entry.Data[heartbeatField] != true {
+		t.Errorf("Unexpected heartbeat entry %v", entry)
+	}
+}
@@ -0,0 +1,84 @@
+package elogrus
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Observer receives measurements of the requests sent by a hook,
+// e.g. to feed a metrics system
+type Observer interface {
+	// ObserveRequest is called after every request sending entries to
+	// ElasticSearch with the number of entries, the time the request
+	// took and its error
+	ObserveRequest(entries int, latency time.Duration, err error)
+}
+
+// FlushObserver is an Observer which is also notified about flushes
+type FlushObserver interface {
+	Observer
+	// ObserveFlush is called after every flush with the
+	// time it took and the error returned by it
+	ObserveFlush(latency time.Duration, err error)
+}
+
+// SlowDeliveryError is passed to the error handler for requests
+// taking longer than the threshold set by WithSlowThreshold. The
+// entries were still delivered, unless another error is reported.
+type SlowDeliveryError struct {
+	Entries int
+	Latency time.Duration
+}
+
+func (e *SlowDeliveryError) Error() string {
+	return fmt.Sprintf("Slow delivery of %d entries took %s", e.Entries, e.Latency)
+}
+
+// WithSlowThreshold makes every request taking longer than threshold
+// pass a *SlowDeliveryError to the error handler, to notice degrading
+// clusters before requests fail
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(hook *ElasticHook) {
+		hook.slowThreshold = threshold
+	}
+}
+
+// AddObserver registers an observer notified about every request
+// and, if it implements FlushObserver, every flush
+func (hook *ElasticHook) AddObserver(observer Observer) {
+	hook.observerMu.Lock()
+	hook.observers = append(hook.observers, observer)
+	hook.observerMu.Unlock()
+}
+
+// observeRequest passes the outcome of a request to the observers
+func (hook *ElasticHook) observeRequest(entries int, start time.Time, err error) {
+	hook.observerMu.RLock()
+	observers := hook.observers
+	hook.observerMu.RUnlock()
+
+	latency := time.Since(start)
+	for _, observer := range observers {
+		observer.ObserveRequest(entries, latency, err)
+	}
+	if hook.slowThreshold > 0 && latency > hook.slowThreshold {
+		hook.diagnose(logrus.WarnLevel, logrus.Fields{"entries": entries, "latency": latency}, "Slow delivery")
+		hook.reportError(&SlowDeliveryError{Entries: entries, Latency: latency}, nil)
+	}
+}
+
+// observeFlush passes the outcome of a flush to the flush observers
+func (hook *ElasticHook) observeFlush(start time.Time, err error) {
+	hook.observerMu.RLock()
+	observers := hook.observers
+	hook.observerMu.RUnlock()
+
+	latency := time.Since(start)
+	for _, observer := range observers {
+		if o, ok := observer.(FlushObserver); ok {
+			o.ObserveFlush(latency, err)
+		}
+	}
+}
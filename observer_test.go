@@ -0,0 +1,41 @@
+package elogrus
+
+import (
+	"testing"
+	"time"
+)
+
+// requestRecorder is an Observer counting the observed entries
+type requestRecorder struct {
+	entries int
+}
+
+func (r *requestRecorder) ObserveRequest(entries int, latency time.Duration, err error) {
+	r.entries += entries
+}
+
+func TestObserver(t *testing.T) {
+	hook := &ElasticHook{}
+	r := new(requestRecorder)
+	hook.AddObserver(r)
+
+	hook.observeRequest(3, time.Now(), nil)
+	if r.entries != 3 {
+		t.Errorf("Expected 3 observed entries got %d", r.entries)
+	}
+}
+
+func TestSlowThreshold(t *testing.T) {
+	hook := &ElasticHook{errors: make(chan error, 1)}
+	WithSlowThreshold(time.Millisecond)(hook)
+
+	hook.observeRequest(1, time.Now().Add(-time.Second), nil)
+	select {
+	case err := <-hook.Errors():
+		if _, ok := err.(*SlowDeliveryError); !ok {
+			t.Errorf("Expected SlowDeliveryError got %v", err)
+		}
+	default:
+		t.Error("Slow delivery was not reported")
+	}
+}
@@ -0,0 +1,68 @@
+package elogrus
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// opaqueIDHeader is shown by the slow logs and the tasks API of ElasticSearch
+const opaqueIDHeader = "X-Opaque-Id"
+
+// WithOpaqueID sends an X-Opaque-Id header with every request, so
+// slow or failed requests can be found in the slow logs and the tasks
+// API of ElasticSearch. Single entries use the value of the given
+// field as ID, if they have one, bulk requests the value of the first
+// entry having one. Requests without the field get a random UUID.
+func WithOpaqueID(field string) Option {
+	return func(hook *ElasticHook) {
+		hook.opaqueID = true
+		hook.opaqueIDField = field
+	}
+}
+
+// correlationID returns the value of the
+// correlation field of the entry if present
+func (hook *ElasticHook) correlationID(entry *logrus.Entry) string {
+	if hook.opaqueIDField == "" {
+		return ""
+	}
+	if id, ok := entry.Data[hook.opaqueIDField]; ok && id != nil {
+		return fmt.Sprint(id)
+	}
+	return ""
+}
+
+// opaqueHeaders returns the headers carrying the opaque ID
+// of a request, using the given ID or a random UUID
+func (hook *ElasticHook) opaqueHeaders(id string) http.Header {
+	if !hook.opaqueID {
+		return nil
+	}
+	if id == "" {
+		id = newUUID()
+	}
+	return http.Header{opaqueIDHeader: []string{id}}
+}
+
+// batchCorrelationID returns the correlation
+// ID of the first document having one
+func batchCorrelationID(docs []*document) string {
+	for _, doc := range docs {
+		if doc.opaqueID != "" {
+			return doc.opaqueID
+		}
+	}
+	return ""
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,53 @@
+package elogrus
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestOpaqueHeaders(t *testing.T) {
+	hook := &ElasticHook{}
+	if headers := hook.opaqueHeaders("abc"); headers != nil {
+		t.Errorf("Expected no headers by default got %v", headers)
+	}
+
+	WithOpaqueID("request_id")(hook)
+	entry := logrus.WithField("request_id", "abc")
+	if id := hook.opaqueHeaders(hook.correlationID(entry)).Get(opaqueIDHeader); id != "abc" {
+		t.Errorf("Expected opaque ID abc got %s", id)
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := hook.opaqueHeaders("").Get(opaqueIDHeader); !uuid.MatchString(id) {
+		t.Errorf("Expected random UUID got %s", id)
+	}
+}
+
+func TestBulkOpaqueID(t *testing.T) {
+	ids := make(chan string, 1)
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/_bulk" {
+			ids <- r.Header.Get(opaqueIDHeader)
+		}
+		return false
+	})
+	defer server.Close()
+	hook, err := NewBulkElasticHook(client, "localhost", logrus.DebugLevel, "log", WithOpaqueID("request_id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close(context.TODO())
+
+	correlated := infoEntry().WithField("request_id", "abc")
+	correlated.Level = logrus.InfoLevel
+	if err := hook.FireBatch([]*logrus.Entry{infoEntry(), correlated}); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-ids; id != "abc" {
+		t.Errorf("Expected the correlation ID as opaque ID got %s", id)
+	}
+}
@@ -0,0 +1,30 @@
+package elogrus
+
+import "github.com/sirupsen/logrus"
+
+// EntryProcessor is called for every entry before its document is
+// created. It returns the entry to send, which may be changed or
+// replaced, or nil to veto the entry, which is then skipped silently.
+// Processors changing the entry should change a copy, as the entry is
+// passed to the other hooks of the logger as well.
+type EntryProcessor func(entry *logrus.Entry) *logrus.Entry
+
+// WithEntryProcessors appends the processors to the chain of
+// processors called in order for every entry, e.g. to enrich,
+// redact or sample entries
+func WithEntryProcessors(processors ...EntryProcessor) Option {
+	return func(hook *ElasticHook) {
+		hook.processors = append(hook.processors, processors...)
+	}
+}
+
+// process passes the entry through the chain of
+// processors and returns nil if it was vetoed
+func (hook *ElasticHook) process(entry *logrus.Entry) *logrus.Entry {
+	for _, processor := range hook.processors {
+		if entry = processor(entry); entry == nil {
+			return nil
+		}
+	}
+	return entry
+}
@@ -0,0 +1,47 @@
+package elogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEntryProcessors(t *testing.T) {
+	var calls []string
+	hook := &ElasticHook{}
+	WithEntryProcessors(
+		func(entry *logrus.Entry) *logrus.Entry {
+			calls = append(calls, "sample")
+			if entry.Level == logrus.DebugLevel {
+				return nil
+			}
+			return entry
+		},
+		func(entry *logrus.Entry) *logrus.Entry {
+			calls = append(calls, "enrich")
+			return entry.WithField("region", "eu")
+		},
+	)(hook)
+
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Data: logrus.Fields{}}
+	doc, err := hook.newDocument(entry, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.entry.Data["region"] != "eu" {
+		t.Errorf("Expected processed entry got %v", doc.entry.Data)
+	}
+	if _, ok := entry.Data["region"]; ok {
+		t.Error("The original entry must not be changed")
+	}
+	if len(calls) != 2 || calls[0] != "sample" || calls[1] != "enrich" {
+		t.Errorf("Unexpected calls %v", calls)
+	}
+
+	if _, err := hook.newDocument(&logrus.Entry{Level: logrus.DebugLevel}, "index"); err != ErrSkipEntry {
+		t.Errorf("Expected vetoed entry got %v", err)
+	}
+	if err := ignoreSkipped(ErrSkipEntry); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
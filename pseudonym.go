@@ -0,0 +1,44 @@
+package elogrus
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithPseudonymization replaces the values of the fields matching one of
+// the names or glob patterns with a salted hash (HMAC-SHA256), so entries
+// can still be correlated, e.g. per user, without storing the values.
+// Names are matched like by WithRedaction. Keep the salt secret.
+func WithPseudonymization(salt []byte, patterns ...string) Option {
+	return func(hook *ElasticHook) {
+		hook.pseudonymSalt = salt
+		for _, pattern := range patterns {
+			hook.pseudonyms = append(hook.pseudonyms, strings.ToLower(pattern))
+		}
+	}
+}
+
+// pseudonym returns the salted hash of the value
+func (hook *ElasticHook) pseudonym(value interface{}) string {
+	mac := hmac.New(sha256.New, hook.pseudonymSalt)
+	fmt.Fprint(mac, value)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pseudonymizeFields returns the fields with the
+// matching values replaced by their pseudonym
+func (hook *ElasticHook) pseudonymizeFields(data logrus.Fields) logrus.Fields {
+	pseudonymized := make(logrus.Fields, len(data))
+	for k, v := range data {
+		if v != nil && matchesAny(hook.pseudonyms, k) {
+			v = hook.pseudonym(v)
+		}
+		pseudonymized[k] = v
+	}
+	return pseudonymized
+}
@@ -0,0 +1,35 @@
+package elogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithPseudonymization(t *testing.T) {
+	hook := &ElasticHook{}
+	WithPseudonymization([]byte("salt"), "user_id", "*email*")(hook)
+
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{"user_id": 42, "Email": "joe@example.com", "name": "joe"}})
+	if data["name"] != "joe" {
+		t.Errorf("Unexpected name %v", data["name"])
+	}
+	id, ok := data["user_id"].(string)
+	if !ok || len(id) != 64 {
+		t.Errorf("Expected hash for user_id got %v", data["user_id"])
+	}
+	if data["Email"] == "joe@example.com" {
+		t.Error("Expected hash for Email")
+	}
+
+	again := hook.entryData(&logrus.Entry{Data: logrus.Fields{"user_id": 42}})
+	if again["user_id"] != id {
+		t.Error("Expected the same pseudonym for the same value")
+	}
+
+	other := &ElasticHook{}
+	WithPseudonymization([]byte("pepper"), "user_id")(other)
+	if other.entryData(&logrus.Entry{Data: logrus.Fields{"user_id": 42}})["user_id"] == id {
+		t.Error("Expected a different pseudonym for a different salt")
+	}
+}
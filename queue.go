@@ -0,0 +1,267 @@
+package elogrus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultQueueSize = 1000
+
+// DropPolicy defines which entry is discarded
+// when the queue of an asynchronous hook is full
+type DropPolicy int
+
+const (
+	// DropNewest rejects the entry being fired
+	DropNewest DropPolicy = iota
+	// DropOldest evicts the oldest queued entry to make room for the
+	// entry being fired. Entries below the priority level set by
+	// WithPriorityLevel never evict entries of or above it.
+	DropOldest
+	// Block makes Fire wait for free space in the queue, at most for
+	// the timeout set by WithBlockTimeout, before failing with ErrQueueFull
+	Block
+)
+
+func (p DropPolicy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop oldest"
+	case Block:
+		return "block"
+	}
+	return "drop newest"
+}
+
+// DropReason tells why an entry was discarded
+type DropReason string
+
+const (
+	// DropReasonQueueFull means the entry was rejected because the queue was full
+	DropReasonQueueFull DropReason = "queue_full"
+	// DropReasonEvicted means the entry was evicted from the
+	// queue to make room for a newer or more important one
+	DropReasonEvicted DropReason = "evicted"
+)
+
+// DropHandler is called for every entry discarded by the hook
+type DropHandler func(entry *logrus.Entry, reason DropReason)
+
+// WithDropHandler sets a function called for every entry which is
+// discarded because the queue is full
+func WithDropHandler(handler DropHandler) Option {
+	return func(hook *ElasticHook) {
+		hook.dropHandler = handler
+	}
+}
+
+// queue is a bounded FIFO of documents waiting to be sent by the
+// hook's workers. Priority documents are kept in a separate lane
+// which is always served first.
+type queue struct {
+	mu       sync.Mutex
+	items    []*document
+	urgent   []*document
+	capacity int
+	// maxBytes limits the total size of the queued
+	// document bodies, zero means no limit
+	maxBytes int
+	bytes    int
+	policy   DropPolicy
+	timeout  time.Duration
+	// ready is signalled whenever documents are available
+	ready chan struct{}
+	// space is signalled whenever documents are removed
+	space chan struct{}
+}
+
+func newQueue(capacity, maxBytes int, policy DropPolicy, timeout time.Duration) *queue {
+	return &queue{
+		items:    make([]*document, 0, capacity),
+		urgent:   make([]*document, 0),
+		capacity: capacity,
+		maxBytes: maxBytes,
+		policy:   policy,
+		timeout:  timeout,
+		ready:    make(chan struct{}, 1),
+		space:    make(chan struct{}, 1),
+	}
+}
+
+// push appends the document to the queue. If there is no space left
+// it either returns ErrQueueFull, returns the evicted oldest documents
+// or waits for space, depending on the drop policy.
+func (q *queue) push(ctx context.Context, doc *document) ([]*document, error) {
+	var timeout <-chan time.Time
+	for {
+		dropped, err := q.tryPush(doc)
+		if err != ErrQueueFull || q.policy != Block {
+			return dropped, err
+		}
+
+		if timeout == nil && q.timeout > 0 {
+			timer := time.NewTimer(q.timeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-q.space:
+		case <-timeout:
+			return nil, ErrQueueFull
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// pushWait appends the document to the queue, waiting for free
+// space regardless of the drop policy
+func (q *queue) pushWait(ctx context.Context, doc *document) error {
+	for {
+		q.mu.Lock()
+		if q.fits(doc) {
+			free := q.add(doc)
+			q.mu.Unlock()
+			q.notify(free)
+			return nil
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (q *queue) tryPush(doc *document) ([]*document, error) {
+	var dropped []*document
+
+	q.mu.Lock()
+	for !q.fits(doc) {
+		switch {
+		case doc.priority && len(q.items) > 0:
+			// Priority documents preempt regular ones
+			dropped = append(dropped, q.shift(&q.items))
+		case q.policy == DropOldest && len(q.items) > 0:
+			dropped = append(dropped, q.shift(&q.items))
+		case q.policy == DropOldest && doc.priority:
+			// Regular documents never evict priority ones
+			dropped = append(dropped, q.shift(&q.urgent))
+		default:
+			// Put back the documents evicted in vain
+			for _, d := range dropped {
+				q.bytes += len(d.body)
+			}
+			q.items = append(dropped, q.items...)
+			q.mu.Unlock()
+			return nil, ErrQueueFull
+		}
+	}
+	free := q.add(doc)
+	q.mu.Unlock()
+
+	q.notify(free)
+	return dropped, nil
+}
+
+// fits reports whether the document can be added without exceeding
+// the capacity or the memory budget. A document larger than the whole
+// budget is still accepted by an empty queue. Must be called with
+// q.mu held.
+func (q *queue) fits(doc *document) bool {
+	if len(q.items)+len(q.urgent) >= q.capacity {
+		return false
+	}
+	return q.maxBytes <= 0 || q.bytes == 0 || q.bytes+len(doc.body) <= q.maxBytes
+}
+
+// add appends the document to its lane and reports
+// whether there is space left. Must be called with q.mu held.
+func (q *queue) add(doc *document) bool {
+	if doc.priority {
+		q.urgent = append(q.urgent, doc)
+	} else {
+		q.items = append(q.items, doc)
+	}
+	q.bytes += len(doc.body)
+	return len(q.items)+len(q.urgent) < q.capacity && (q.maxBytes <= 0 || q.bytes < q.maxBytes)
+}
+
+// notify wakes up a consumer and, if there is
+// more space, the next waiting producer
+func (q *queue) notify(free bool) {
+	q.signal(q.ready)
+	if free {
+		q.signal(q.space)
+	}
+}
+
+// tryPop removes the oldest document from the queue without
+// blocking, preferring documents from the priority lane
+func (q *queue) tryPop() (*document, bool) {
+	var doc *document
+
+	q.mu.Lock()
+	switch {
+	case len(q.urgent) > 0:
+		doc = q.shift(&q.urgent)
+	case len(q.items) > 0:
+		doc = q.shift(&q.items)
+	default:
+		q.mu.Unlock()
+		return nil, false
+	}
+	remaining := len(q.items) + len(q.urgent)
+	q.mu.Unlock()
+
+	// Wake up the next consumer if there is more work
+	if remaining > 0 {
+		q.signal(q.ready)
+	}
+	q.signal(q.space)
+	return doc, true
+}
+
+// len returns the number of queued documents
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items) + len(q.urgent)
+}
+
+// oldest returns the creation time of the oldest queued
+// document or the zero time if the queue is empty
+func (q *queue) oldest() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var oldest time.Time
+	for _, lane := range [][]*document{q.items, q.urgent} {
+		if len(lane) > 0 && (oldest.IsZero() || lane[0].created.Before(oldest)) {
+			oldest = lane[0].created
+		}
+	}
+	return oldest
+}
+
+// shift removes and returns the first document of
+// the lane. Must be called with q.mu held.
+func (q *queue) shift(lane *[]*document) *document {
+	doc := (*lane)[0]
+	(*lane)[0] = nil
+	*lane = (*lane)[1:]
+	q.bytes -= len(doc.body)
+	return doc
+}
+
+func (q *queue) signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,190 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestQueueFIFO(t *testing.T) {
+	q := newQueue(3, 0, DropNewest, 0)
+	for _, index := range []string{"a", "b", "c"} {
+		if _, err := q.push(context.TODO(), &document{index: index}); err != nil {
+			t.Fatalf("Unexpected push error: %s", err)
+		}
+	}
+
+	for _, expected := range []string{"a", "b", "c"} {
+		doc, ok := q.tryPop()
+		if !ok {
+			t.Fatal("Queue empty too early")
+		}
+		if doc.index != expected {
+			t.Errorf("Wrong order: expected %s got %s", expected, doc.index)
+		}
+	}
+
+	if _, ok := q.tryPop(); ok {
+		t.Error("Queue should be empty")
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := newQueue(1, 0, DropNewest, 0)
+	if _, err := q.push(context.TODO(), &document{}); err != nil {
+		t.Fatalf("Unexpected push error: %s", err)
+	}
+	if _, err := q.push(context.TODO(), &document{}); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull got %v", err)
+	}
+	if q.len() != 1 {
+		t.Errorf("Expected 1 queued document got %d", q.len())
+	}
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	q := newQueue(2, 0, DropOldest, 0)
+	for _, index := range []string{"a", "b"} {
+		if _, err := q.push(context.TODO(), &document{index: index}); err != nil {
+			t.Fatalf("Unexpected push error: %s", err)
+		}
+	}
+
+	dropped, err := q.push(context.TODO(), &document{index: "c"})
+	if err != nil {
+		t.Fatalf("Unexpected push error: %s", err)
+	}
+	if len(dropped) != 1 || dropped[0].index != "a" {
+		t.Errorf("Expected oldest document to be dropped got %v", dropped)
+	}
+
+	for _, expected := range []string{"b", "c"} {
+		doc, ok := q.tryPop()
+		if !ok || doc.index != expected {
+			t.Errorf("Expected %s got %v", expected, doc)
+		}
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	q := newQueue(1, 0, Block, 50*time.Millisecond)
+	if _, err := q.push(context.TODO(), &document{index: "a"}); err != nil {
+		t.Fatalf("Unexpected push error: %s", err)
+	}
+
+	start := time.Now()
+	if _, err := q.push(context.TODO(), &document{index: "b"}); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull got %v", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("Push returned before the timeout elapsed")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.tryPop()
+	}()
+	if _, err := q.push(context.TODO(), &document{index: "c"}); err != nil {
+		t.Errorf("Expected push to succeed after pop got %v", err)
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := newQueue(2, 0, DropNewest, 0)
+	for _, index := range []string{"a", "b"} {
+		if _, err := q.push(context.TODO(), &document{index: index}); err != nil {
+			t.Fatalf("Unexpected push error: %s", err)
+		}
+	}
+
+	dropped, err := q.push(context.TODO(), &document{index: "urgent", priority: true})
+	if err != nil {
+		t.Fatalf("Priority document rejected: %s", err)
+	}
+	if len(dropped) != 1 || dropped[0].index != "a" {
+		t.Errorf("Expected oldest regular document to be dropped got %v", dropped)
+	}
+
+	for _, expected := range []string{"urgent", "b"} {
+		doc, ok := q.tryPop()
+		if !ok || doc.index != expected {
+			t.Errorf("Expected %s got %v", expected, doc)
+		}
+	}
+}
+
+func TestQueuePriorityDropOldest(t *testing.T) {
+	q := newQueue(2, 0, DropOldest, 0)
+	for _, index := range []string{"e1", "e2"} {
+		if _, err := q.push(context.TODO(), &document{index: index, priority: true}); err != nil {
+			t.Fatalf("Unexpected push error: %s", err)
+		}
+	}
+
+	if _, err := q.push(context.TODO(), &document{index: "debug"}); err != ErrQueueFull {
+		t.Errorf("Expected regular document to be rejected got %v", err)
+	}
+	dropped, err := q.push(context.TODO(), &document{index: "e3", priority: true})
+	if err != nil {
+		t.Fatalf("Priority document rejected: %s", err)
+	}
+	if len(dropped) != 1 || dropped[0].index != "e1" {
+		t.Errorf("Expected oldest priority document to be dropped got %v", dropped)
+	}
+	for _, expected := range []string{"e2", "e3"} {
+		doc, ok := q.tryPop()
+		if !ok || doc.index != expected {
+			t.Errorf("Expected %s got %v", expected, doc)
+		}
+	}
+}
+
+func TestQueueBytes(t *testing.T) {
+	q := newQueue(10, 8, DropOldest, 0)
+	for _, index := range []string{"a", "b"} {
+		if _, err := q.push(context.TODO(), &document{index: index, body: []byte("{abc}")}); err != nil {
+			t.Fatalf("Unexpected push error: %s", err)
+		}
+	}
+	if q.len() != 1 {
+		t.Errorf("Expected 1 queued document got %d", q.len())
+	}
+
+	// Documents larger than the budget are accepted by an empty queue
+	dropped, err := q.push(context.TODO(), &document{index: "c", body: []byte("{abcdefgh}")})
+	if err != nil {
+		t.Fatalf("Unexpected push error: %s", err)
+	}
+	if len(dropped) != 1 || dropped[0].index != "b" {
+		t.Errorf("Expected document b to be dropped got %v", dropped)
+	}
+}
+
+func TestQueueBytesFull(t *testing.T) {
+	q := newQueue(10, 8, DropNewest, 0)
+	if _, err := q.push(context.TODO(), &document{body: []byte("{abc}")}); err != nil {
+		t.Fatalf("Unexpected push error: %s", err)
+	}
+	if _, err := q.push(context.TODO(), &document{body: []byte("{abc}")}); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull got %v", err)
+	}
+}
+
+func TestDropHandler(t *testing.T) {
+	var reasons []DropReason
+	hook := &ElasticHook{
+		dropHandler: func(entry *logrus.Entry, reason DropReason) {
+			reasons = append(reasons, reason)
+		},
+	}
+
+	hook.drop([]*document{{}, {}}, DropReasonEvicted)
+	if len(reasons) != 2 || reasons[0] != DropReasonEvicted {
+		t.Errorf("Expected 2 evicted entries got %v", reasons)
+	}
+	if hook.completed != 2 {
+		t.Errorf("Expected 2 completed documents got %d", hook.completed)
+	}
+}
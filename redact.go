@@ -0,0 +1,98 @@
+package elogrus
+
+import (
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redactedValue replaces the values of redacted fields
+const redactedValue = "[REDACTED]"
+
+// WithRedaction replaces the values of the fields matching one of the
+// names or glob patterns like "*password*" with "[REDACTED]". Names
+// are matched case-insensitively, after flattening. Fields of nested
+// maps, structs and slices are matched by their name and by their
+// dotted path like "user.password".
+func WithRedaction(patterns ...string) Option {
+	return func(hook *ElasticHook) {
+		for _, pattern := range patterns {
+			hook.redactions = append(hook.redactions, strings.ToLower(pattern))
+		}
+	}
+}
+
+// redacts reports whether the value of the field is redacted
+func (hook *ElasticHook) redacts(name string) bool {
+	return matchesAny(hook.redactions, name)
+}
+
+// matchesAny reports whether the name matches one of the lower
+// case names or glob patterns, ignoring case
+func matchesAny(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok || pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// redactFields returns the fields with the redacted values replaced
+func (hook *ElasticHook) redactFields(data logrus.Fields) logrus.Fields {
+	redacted := make(logrus.Fields, len(data))
+	for k, v := range data {
+		if hook.redacts(k) {
+			v = redactedValue
+		} else {
+			v, _ = hook.redactValue(k, v, maxSafeDepth)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// redactValue returns the value of the field with the values of nested
+// fields replaced and whether any were. Objects containing redacted
+// fields are returned as maps, other values are returned unchanged.
+func (hook *ElasticHook) redactValue(name string, value interface{}, depth int) (interface{}, bool) {
+	if depth <= 0 || value == nil {
+		return value, false
+	}
+	if object, ok := objectOf(value); ok {
+		redacted := make(map[string]interface{}, len(object))
+		changed := false
+		for k, v := range object {
+			path := name + "." + k
+			if hook.redacts(k) || hook.redacts(path) {
+				v, changed = redactedValue, true
+			} else if r, ok := hook.redactValue(path, v, depth-1); ok {
+				v, changed = r, true
+			}
+			redacted[k] = v
+		}
+		if !changed {
+			return value, false
+		}
+		return redacted, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || !mayConvert(rv.Type().Elem()) {
+		return value, false
+	}
+	redacted := make([]interface{}, rv.Len())
+	changed := false
+	for i := range redacted {
+		v, ok := hook.redactValue(name, rv.Index(i).Interface(), depth-1)
+		redacted[i] = v
+		changed = changed || ok
+	}
+	if !changed {
+		return value, false
+	}
+	return redacted, true
+}
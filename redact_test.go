@@ -0,0 +1,76 @@
+package elogrus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithRedaction(t *testing.T) {
+	hook := &ElasticHook{}
+	WithRedaction("*password*", "Token")(hook)
+	WithFlattening(1)(hook)
+
+	entry := &logrus.Entry{Data: logrus.Fields{
+		"DB_Password": "secret",
+		"token":       "abc",
+		"user":        logrus.Fields{"password": "secret", "name": "joe"},
+		"tokens":      3,
+	}}
+	data := hook.entryData(entry)
+	for k, expected := range map[string]interface{}{
+		"DB_Password":   redactedValue,
+		"token":         redactedValue,
+		"user.password": redactedValue,
+		"user.name":     "joe",
+		"tokens":        3,
+	} {
+		if data[k] != expected {
+			t.Errorf("Expected %v for %s got %v", expected, k, data[k])
+		}
+	}
+	if entry.Data["token"] != "abc" {
+		t.Error("Fields of the entry must not be redacted")
+	}
+}
+
+type credentials struct {
+	ID       int64
+	User     string
+	Password string `json:"password"`
+}
+
+func TestWithRedactionNested(t *testing.T) {
+	hook := &ElasticHook{}
+	WithRedaction("*password*", "req.token")(hook)
+
+	req := map[string]interface{}{
+		"password": "x",
+		"token":    "abc",
+		"body":     []interface{}{logrus.Fields{"db_password": "y", "n": 1}},
+		"login":    &credentials{ID: 9007199254740993, User: "joe", Password: "z"},
+		"user":     map[string]string{"name": "joe"},
+	}
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{"req": req, "other": []int{1}}})
+	redacted, ok := data["req"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected redacted map got %#v", data["req"])
+	}
+	body, _ := redacted["body"].([]interface{})
+	login, _ := redacted["login"].(map[string]interface{})
+	if redacted["password"] != redactedValue || redacted["token"] != redactedValue ||
+		len(body) != 1 || body[0].(map[string]interface{})["db_password"] != redactedValue ||
+		login["password"] != redactedValue || login["User"] != "joe" {
+		t.Errorf("Expected nested fields to be redacted got %#v", redacted)
+	}
+	if id := fmt.Sprint(login["ID"]); id != "9007199254740993" {
+		t.Errorf("Expected the ID to keep its precision got %s", id)
+	}
+	if _, ok := redacted["user"].(map[string]string); !ok {
+		t.Errorf("Expected objects without redacted fields to be kept got %#v", redacted["user"])
+	}
+	if req["password"] != "x" {
+		t.Error("Fields of the entry must not be redacted")
+	}
+}
@@ -0,0 +1,153 @@
+package elogrus
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/sirupsen/logrus"
+)
+
+// RetryPolicy defines how often and how fast
+// failed requests to ElasticSearch are repeated
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts
+	// including the first one
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry.
+	// It doubles with every further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay, between 0 and 1,
+	// which is randomized to spread retries of several hooks
+	Jitter float64
+}
+
+// defaultRetryAfter is the minimum delay after ElasticSearch
+// rejected a request with 429 Too Many Requests
+// without telling when to try again
+const defaultRetryAfter = time.Second
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.2,
+}
+
+// WithRetryPolicy sets the policy used to repeat failed requests.
+// A policy with MaxAttempts of 1 disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(hook *ElasticHook) {
+		if policy.MaxAttempts < 1 {
+			policy.MaxAttempts = 1
+		}
+		hook.retry = policy
+	}
+}
+
+// delay returns the time to wait after the given failed attempt
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(p.Jitter * rand.Float64() * float64(d))
+	}
+	return d
+}
+
+// withRetry calls send until it succeeds, fails
+// permanently or the retry policy is exhausted
+func (hook *ElasticHook) withRetry(send func() (*elastic.Response, error)) (*elastic.Response, error) {
+	for attempt := 1; ; attempt++ {
+		end := func(err error) {}
+		if attempt > 1 {
+			end = hook.startSpan(hook.ctx, "elogrus.retry", map[string]interface{}{"attempt": attempt})
+		}
+		res, err := send()
+		end(err)
+		if err == nil || attempt >= hook.retry.MaxAttempts || !retriableError(err) {
+			return res, err
+		}
+		delay := hook.retryDelay(attempt, res, err)
+		hook.diagnose(logrus.InfoLevel, logrus.Fields{"attempt": attempt, "delay": delay, "error": err}, "Retrying request")
+		if !hook.wait(delay) {
+			return res, err
+		}
+		atomic.AddInt64(&hook.retried, 1)
+	}
+}
+
+// retryDelay returns the time to wait after the given failed attempt.
+// Requests rejected with 429 Too Many Requests wait at least as long
+// as requested by the Retry-After header.
+func (hook *ElasticHook) retryDelay(attempt int, res *elastic.Response, err error) time.Duration {
+	d := hook.retry.delay(attempt)
+	if !elastic.IsStatusCode(err, http.StatusTooManyRequests) && (res == nil || res.StatusCode != http.StatusTooManyRequests) {
+		return d
+	}
+
+	after := defaultRetryAfter
+	if res != nil {
+		after = retryAfter(res.Header)
+	}
+	if after > d {
+		return after
+	}
+	return d
+}
+
+// retryAfter parses the Retry-After header which
+// is either a number of seconds or a HTTP date
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return defaultRetryAfter
+}
+
+// wait pauses for the given duration and reports
+// false if the hook was cancelled in the meantime
+func (hook *ElasticHook) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-hook.ctx.Done():
+		return false
+	}
+}
+
+// retriableError reports whether a failed request can be repeated
+func retriableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var e *elastic.Error
+	if errors.As(err, &e) {
+		return retriableStatus(e.Status)
+	}
+	return true
+}
+
+// retriableStatus reports whether a request
+// failing with the given status can be repeated
+func retriableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
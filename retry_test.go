@@ -0,0 +1,74 @@
+package elogrus
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+)
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+
+	for attempt, expected := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if d := policy.delay(attempt); d != expected {
+			t.Errorf("Wrong delay for attempt %d: expected %s got %s", attempt, expected, d)
+		}
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	policy := RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		Jitter:    0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := policy.delay(1); d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("Delay out of jitter range: %s", d)
+		}
+	}
+}
+
+func TestRetriableError(t *testing.T) {
+	for err, expected := range map[error]bool{
+		&elastic.Error{Status: http.StatusTooManyRequests}:    true,
+		&elastic.Error{Status: http.StatusServiceUnavailable}: true,
+		&elastic.Error{Status: http.StatusBadRequest}:         false,
+		fmt.Errorf("connection refused"):                      true,
+	} {
+		if retriableError(err) != expected {
+			t.Errorf("Expected retriable=%t for %v", expected, err)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	if d := retryAfter(header); d != defaultRetryAfter {
+		t.Errorf("Expected default delay got %s", d)
+	}
+
+	header.Set("Retry-After", "7")
+	if d := retryAfter(header); d != 7*time.Second {
+		t.Errorf("Expected 7s got %s", d)
+	}
+
+	header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d := retryAfter(header); d < 58*time.Second || d > time.Minute {
+		t.Errorf("Expected about a minute got %s", d)
+	}
+}
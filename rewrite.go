@@ -0,0 +1,18 @@
+package elogrus
+
+import "github.com/sirupsen/logrus"
+
+// DocumentRewriter receives the serialized document of an entry and
+// returns the document to send, e.g. wrapped in an envelope or with a
+// signature field added. Returning ErrSkipEntry skips the entry
+// silently, other errors and documents which are not valid JSON are
+// passed to the error handler and the entry is not sent.
+type DocumentRewriter func(body []byte, entry *logrus.Entry) ([]byte, error)
+
+// WithDocumentRewriter sets the function rewriting the serialized
+// documents before they are queued or sent
+func WithDocumentRewriter(rewriter DocumentRewriter) Option {
+	return func(hook *ElasticHook) {
+		hook.rewriter = rewriter
+	}
+}
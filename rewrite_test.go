@@ -0,0 +1,47 @@
+package elogrus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithDocumentRewriter(t *testing.T) {
+	var reported error
+	hook := &ElasticHook{}
+	hook.SetErrorHandler(func(err error, entry *logrus.Entry) {
+		reported = err
+	})
+	WithMessageCreator(func(entry *logrus.Entry, hook *ElasticHook) interface{} {
+		return entry.Message
+	})(hook)
+	WithDocumentRewriter(func(body []byte, entry *logrus.Entry) ([]byte, error) {
+		switch entry.Message {
+		case "skip":
+			return nil, ErrSkipEntry
+		case "fail":
+			return nil, fmt.Errorf("Cannot sign document")
+		case "invalid":
+			return []byte(`{"payload":`), nil
+		}
+		return []byte(fmt.Sprintf("{\n\t\"payload\": %s,\n\t\"signature\": \"abc\"\n}", body)), nil
+	})(hook)
+
+	doc, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.body) != `{"payload":"Hello","signature":"abc"}` {
+		t.Errorf("Unexpected body %s", doc.body)
+	}
+	if _, err := hook.newDocument(&logrus.Entry{Message: "skip"}, "index"); err != ErrSkipEntry || reported != nil {
+		t.Errorf("Expected entry to be skipped silently got %v, %v", err, reported)
+	}
+	for _, message := range []string{"fail", "invalid"} {
+		reported = nil
+		if _, err := hook.newDocument(&logrus.Entry{Message: message}, "index"); err == nil || reported != err {
+			t.Errorf("Expected error to be reported got %v, %v", err, reported)
+		}
+	}
+}
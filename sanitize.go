@@ -0,0 +1,40 @@
+package elogrus
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FieldNameSanitizer rewrites the name of a field
+type FieldNameSanitizer func(name string) string
+
+// WithFieldNameSanitizer rewrites the names of the fields of the entries
+// using the sanitizer, preventing mapping conflicts. Names given in the
+// rename table are not sanitized.
+func WithFieldNameSanitizer(sanitizer FieldNameSanitizer) Option {
+	return func(hook *ElasticHook) {
+		hook.sanitizer = sanitizer
+	}
+}
+
+// ReplaceIllegalChars returns a sanitizer replacing dots and all other
+// characters except letters, digits, "_", "-" and "@" with replacement.
+// Leading underscores, reserved for metadata fields, are removed.
+func ReplaceIllegalChars(replacement string) FieldNameSanitizer {
+	return func(name string) string {
+		var b strings.Builder
+		for _, r := range name {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+				r == '_' || r == '-' || r == '@' || r > unicode.MaxASCII {
+				b.WriteRune(r)
+			} else {
+				b.WriteString(replacement)
+			}
+		}
+		name = strings.TrimLeft(b.String(), "_")
+		if name == "" {
+			return "empty"
+		}
+		return name
+	}
+}
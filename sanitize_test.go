@@ -0,0 +1,39 @@
+package elogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestReplaceIllegalChars(t *testing.T) {
+	sanitize := ReplaceIllegalChars("_")
+	for name, expected := range map[string]string{
+		"user.name":   "user_name",
+		"_id":         "id",
+		"__":          "empty",
+		"a b#c":       "a_b_c",
+		"@timestamp":  "@timestamp",
+		"größe-total": "größe-total",
+	} {
+		if got := sanitize(name); got != expected {
+			t.Errorf("Expected %s for %s got %s", expected, name, got)
+		}
+	}
+}
+
+func TestWithFieldNameSanitizer(t *testing.T) {
+	hook := &ElasticHook{}
+	WithFieldNameSanitizer(ReplaceIllegalChars("_"))(hook)
+	WithFieldRenames(map[string]string{"trace_id": "trace.id"})(hook)
+	WithFlattening(1)(hook)
+
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{
+		"user.name": "joe",
+		"trace_id":  "abc",
+		"http":      logrus.Fields{"_status": 200},
+	}})
+	if len(data) != 3 || data["user_name"] != "joe" || data["trace.id"] != "abc" || data["http.status"] != 200 {
+		t.Errorf("Unexpected data %v", data)
+	}
+}
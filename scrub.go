@@ -0,0 +1,98 @@
+package elogrus
+
+import (
+	"reflect"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// scrubRule replaces the matches of a pattern
+type scrubRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// WithScrubRule replaces all matches of the pattern in the messages
+// with replacement, e.g. to remove email or IP addresses. The
+// replacement may refer to submatches like regexp.ReplaceAllString.
+func WithScrubRule(pattern *regexp.Regexp, replacement string) Option {
+	return func(hook *ElasticHook) {
+		hook.scrubRules = append(hook.scrubRules, scrubRule{pattern, replacement})
+	}
+}
+
+// WithScrubbedFields applies the scrub rules to the string field
+// values as well, including those of nested maps, structs and slices
+func WithScrubbedFields() Option {
+	return func(hook *ElasticHook) {
+		hook.scrubFields = true
+	}
+}
+
+// scrub applies the scrub rules to s
+func (hook *ElasticHook) scrub(s string) string {
+	for _, rule := range hook.scrubRules {
+		s = rule.pattern.ReplaceAllString(s, rule.replacement)
+	}
+	return s
+}
+
+// scrubData returns the fields with the scrub rules applied to
+// the string values, including those of nested objects and slices
+func (hook *ElasticHook) scrubData(data logrus.Fields) logrus.Fields {
+	scrub := func(_, s string) string { return hook.scrub(s) }
+	scrubbed := make(logrus.Fields, len(data))
+	for k, v := range data {
+		scrubbed[k], _ = replaceStrings(k, v, maxSafeDepth, scrub)
+	}
+	return scrubbed
+}
+
+// replaceStrings returns the value with the strings replaced by
+// replace, which is called with the dotted path of the field, and
+// whether any were. Objects containing replaced strings are returned
+// as maps and slices as []interface{}, other values are returned
+// unchanged.
+func replaceStrings(name string, value interface{}, depth int, replace func(field, s string) string) (interface{}, bool) {
+	if s, ok := value.(string); ok {
+		r := replace(name, s)
+		return r, r != s
+	}
+	if depth <= 0 || value == nil {
+		return value, false
+	}
+	if object, ok := objectOf(value); ok {
+		replaced := make(map[string]interface{}, len(object))
+		changed := false
+		for k, v := range object {
+			if r, ok := replaceStrings(name+"."+k, v, depth-1, replace); ok {
+				v, changed = r, true
+			}
+			replaced[k] = v
+		}
+		if !changed {
+			return value, false
+		}
+		return replaced, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return value, false
+	}
+	if elem := rv.Type().Elem(); elem.Kind() != reflect.String && !mayConvert(elem) {
+		return value, false
+	}
+	replaced := make([]interface{}, rv.Len())
+	changed := false
+	for i := range replaced {
+		v, ok := replaceStrings(name, rv.Index(i).Interface(), depth-1, replace)
+		replaced[i] = v
+		changed = changed || ok
+	}
+	if !changed {
+		return value, false
+	}
+	return replaced, true
+}
@@ -0,0 +1,60 @@
+package elogrus
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestScrubbing(t *testing.T) {
+	hook := &ElasticHook{}
+	WithScrubRule(regexp.MustCompile(`[\w.]+@[\w.]+`), "[EMAIL]")(hook)
+	WithScrubRule(regexp.MustCompile(`(\d+)\.\d+\.\d+\.\d+`), "$1.x.x.x")(hook)
+
+	entry := &logrus.Entry{
+		Message: "Login of joe@example.com from 10.1.2.3",
+		Data:    logrus.Fields{"email": "joe@example.com", "age": 42},
+	}
+	msg := createMessage(entry, hook).(map[string]interface{})
+	if msg["Message"] != "Login of [EMAIL] from 10.x.x.x" {
+		t.Errorf("Unexpected message %v", msg["Message"])
+	}
+	if data := msg["Data"].(logrus.Fields); data["email"] != "joe@example.com" {
+		t.Errorf("Expected fields to be unchanged got %v", data)
+	}
+
+	WithScrubbedFields()(hook)
+	data := hook.entryData(entry)
+	if data["email"] != "[EMAIL]" || data["age"] != 42 {
+		t.Errorf("Unexpected data %v", data)
+	}
+}
+
+func TestScrubbingNested(t *testing.T) {
+	hook := &ElasticHook{}
+	WithScrubRule(regexp.MustCompile(`[\w.]+@[\w.]+`), "[EMAIL]")(hook)
+	WithScrubbedFields()(hook)
+
+	user := map[string]interface{}{
+		"contact": logrus.Fields{"email": "joe@example.com"},
+		"cc":      []string{"ann@example.com", "-"},
+		"id":      7,
+	}
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{"user": user, "ids": []int{1}}})
+	scrubbed, ok := data["user"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected scrubbed map got %#v", data["user"])
+	}
+	contact, _ := scrubbed["contact"].(map[string]interface{})
+	cc, _ := scrubbed["cc"].([]interface{})
+	if contact["email"] != "[EMAIL]" || len(cc) != 2 || cc[0] != "[EMAIL]" || cc[1] != "-" || scrubbed["id"] != 7 {
+		t.Errorf("Expected nested strings to be scrubbed got %#v", scrubbed)
+	}
+	if _, ok := data["ids"].([]int); !ok {
+		t.Errorf("Expected values without strings to be kept got %#v", data["ids"])
+	}
+	if user["contact"].(logrus.Fields)["email"] != "joe@example.com" {
+		t.Error("Fields of the entry must not be scrubbed")
+	}
+}
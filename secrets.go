@@ -0,0 +1,93 @@
+package elogrus
+
+import (
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of secrets detected
+const (
+	SecretAWSAccessKey = "aws_access_key"
+	SecretBearerToken  = "bearer_token"
+	SecretCreditCard   = "credit_card"
+)
+
+// SecretHandler is called for every secret detected. The field
+// is empty for secrets detected in the message of the entry.
+type SecretHandler func(kind, field string)
+
+// secretPattern detects a kind of secret
+type secretPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+var secretPatterns = []secretPattern{
+	{SecretAWSAccessKey, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), nil},
+	{SecretBearerToken, regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{8,}=*`), nil},
+	{SecretCreditCard, regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), luhn},
+}
+
+// WithSecretDetection masks AWS access keys, bearer tokens and credit
+// card numbers found in the messages and string fields, including those
+// of nested maps, structs and slices, with "[REDACTED]" as a safety net
+// against logging secrets by accident.
+// The handler, if not nil, is called for every secret detected.
+func WithSecretDetection(handler SecretHandler) Option {
+	return func(hook *ElasticHook) {
+		hook.detectSecrets = true
+		hook.onSecret = handler
+	}
+}
+
+// maskSecrets replaces the secrets found in s
+func (hook *ElasticHook) maskSecrets(field, s string) string {
+	if !hook.detectSecrets {
+		return s
+	}
+	for _, secret := range secretPatterns {
+		s = secret.pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if secret.valid != nil && !secret.valid(match) {
+				return match
+			}
+			if hook.onSecret != nil {
+				hook.onSecret(secret.kind, field)
+			}
+			return redactedValue
+		})
+	}
+	return s
+}
+
+// maskSecretFields returns the fields with the secrets in the string
+// values replaced, including those of nested objects and slices.
+// Secrets in nested fields are reported with the dotted path.
+func (hook *ElasticHook) maskSecretFields(data logrus.Fields) logrus.Fields {
+	masked := make(logrus.Fields, len(data))
+	for k, v := range data {
+		masked[k], _ = replaceStrings(k, v, maxSafeDepth, hook.maskSecrets)
+	}
+	return masked
+}
+
+// luhn reports whether the digits of the number pass the Luhn check
+func luhn(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,142 @@
+package elogrus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithStartupBuffering keeps the hook from failing if ElasticSearch is
+// not reachable when it is created. Entries are buffered in a queue of
+// the size set by WithQueueSize until the index could be created.
+func WithStartupBuffering() Option {
+	return func(hook *ElasticHook) {
+		hook.bufferStartup = true
+	}
+}
+
+// WithStartupRetry makes creating the hook try again to create the
+// index according to the policy if ElasticSearch is not reachable yet.
+// Creating the hook fails if the last attempt failed, unless startup
+// buffering is enabled.
+func WithStartupRetry(policy RetryPolicy) Option {
+	return func(hook *ElasticHook) {
+		hook.startupRetry = policy
+	}
+}
+
+// bootstrapIndex creates the index, trying again
+// according to the startup retry policy
+func (hook *ElasticHook) bootstrapIndex(index string) error {
+	for attempt := 1; ; attempt++ {
+		err := hook.ensureIndex(index)
+		if err == nil || attempt >= hook.startupRetry.MaxAttempts || !retriableError(err) {
+			return err
+		}
+		time.Sleep(hook.startupRetry.delay(attempt))
+	}
+}
+
+// WithLazyInit makes creating the hook skip all requests to
+// ElasticSearch. The index is created right before the first entry
+// is sent instead, so the hook can be created while ElasticSearch
+// is not reachable yet.
+func WithLazyInit() Option {
+	return func(hook *ElasticHook) {
+		hook.lazy = true
+	}
+}
+
+// initIndex creates the index of a lazy hook unless this already
+// succeeded. Failed attempts are repeated with the next request.
+func (hook *ElasticHook) initIndex() error {
+	if atomic.LoadInt32(&hook.initialized) == 1 {
+		return nil
+	}
+
+	hook.initMu.Lock()
+	defer hook.initMu.Unlock()
+	if hook.initialized == 1 {
+		return nil
+	}
+	if err := hook.ensureIndex(hook.index()); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&hook.initialized, 1)
+	return nil
+}
+
+// runBootstrap tries to create the index until ElasticSearch is
+// reachable and releases the entries buffered in the meantime
+func (hook *ElasticHook) runBootstrap(index string) {
+	defer hook.wg.Done()
+
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(hook.retry.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-hook.closing:
+			timer.Stop()
+			return
+		case <-hook.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		err := hook.ensureIndex(index)
+		if err == nil {
+			break
+		}
+		hook.reportError(err, nil)
+	}
+
+	hook.startMu.Lock()
+	close(hook.ready)
+	hook.startMu.Unlock()
+	hook.transition(LifecycleStarted)
+
+	// Synchronous hooks have no workers sending the buffered entries
+	if hook.mode == modeSync {
+		for hook.sendNext() {
+		}
+	}
+}
+
+// awaitReady waits until the index exists and reports
+// false if the hook was cancelled in the meantime
+func (hook *ElasticHook) awaitReady() bool {
+	select {
+	case <-hook.ready:
+		return true
+	case <-hook.closing:
+		return true
+	case <-hook.ctx.Done():
+		return false
+	}
+}
+
+// bufferUntilReady queues the document of a synchronous hook
+// if the index does not exist yet and reports whether it did
+func (hook *ElasticHook) bufferUntilReady(doc *document) (bool, error) {
+	select {
+	case <-hook.ready:
+		return false, nil
+	default:
+	}
+
+	hook.startMu.Lock()
+	defer hook.startMu.Unlock()
+	select {
+	case <-hook.ready:
+		return false, nil
+	default:
+	}
+	dropped, err := hook.queue.tryPush(doc)
+	hook.drop(dropped, DropReasonEvicted)
+	if err == ErrQueueFull {
+		hook.reportDrop(doc, DropReasonQueueFull)
+	}
+	if err == nil {
+		atomic.AddInt64(&hook.enqueued, 1)
+	}
+	return true, err
+}
@@ -0,0 +1,143 @@
+package elogrus
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/sirupsen/logrus"
+)
+
+// newFlakyElastic returns a stub ElasticSearch answering with 503
+// Service Unavailable until up is set, counting the index checks
+func newFlakyElastic(up, checks *int32) (*stubElastic, *elastic.Client) {
+	return newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "HEAD" {
+			atomic.AddInt32(checks, 1)
+		}
+		if atomic.LoadInt32(up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+}
+
+// waitFor polls cond until it holds or a second passed
+func waitFor(cond func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+func infoEntry() *logrus.Entry {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.InfoLevel
+	return entry
+}
+
+func TestStartupBuffering(t *testing.T) {
+	var up, checks int32
+	server, client := newFlakyElastic(&up, &checks)
+	defer server.Close()
+
+	hook, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithStartupBuffering(),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1, BaseDelay: 5 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("Expected the hook to be created while ElasticSearch is down got %s", err)
+	}
+	defer hook.Close(context.TODO())
+
+	for i := 0; i < 2; i++ {
+		if err := hook.Fire(infoEntry()); err != nil {
+			t.Fatalf("Expected the entry to be buffered got %s", err)
+		}
+	}
+	if server.count() != 0 {
+		t.Fatalf("Expected no documents to be sent got %d", server.count())
+	}
+
+	// The synchronous hook sends the buffered entries once ElasticSearch is up
+	atomic.StoreInt32(&up, 1)
+	if !waitFor(func() bool { return server.count() == 2 }) {
+		t.Fatalf("Expected the buffered entries to be sent got %d", server.count())
+	}
+	if err := hook.Fire(infoEntry()); err != nil || server.count() != 3 {
+		t.Errorf("Expected the entry to be sent right away got %v, %d", err, server.count())
+	}
+}
+
+func TestStartupRetry(t *testing.T) {
+	var checks int32
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		// ElasticSearch becomes reachable with the third attempt
+		if r.Method == "HEAD" && atomic.AddInt32(&checks, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	if _, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log", WithStartupRetry(policy)); err == nil {
+		t.Fatal("Expected creating the hook to fail after 2 attempts")
+	}
+	if checks != 2 {
+		t.Errorf("Expected 2 attempts got %d", checks)
+	}
+
+	atomic.StoreInt32(&checks, 0)
+	policy.MaxAttempts = 3
+	hook, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log", WithStartupRetry(policy))
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed got %s", err)
+	}
+	defer hook.Close(context.TODO())
+	if checks != 3 {
+		t.Errorf("Expected 3 attempts got %d", checks)
+	}
+}
+
+func TestLazyInit(t *testing.T) {
+	var up, checks int32
+	server, client := newFlakyElastic(&up, &checks)
+	defer server.Close()
+
+	hook, err := NewElasticHook(client, "localhost", logrus.DebugLevel, "log",
+		WithLazyInit(),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("Expected the hook to be created while ElasticSearch is down got %s", err)
+	}
+	defer hook.Close(context.TODO())
+	if atomic.LoadInt32(&checks) != 0 {
+		t.Fatalf("Expected no requests while creating the hook got %d index checks", checks)
+	}
+
+	if err := hook.Fire(infoEntry()); err == nil {
+		t.Fatal("Expected an error while ElasticSearch is down")
+	}
+
+	// The failed creation of the index is repeated with the next entry
+	atomic.StoreInt32(&up, 1)
+	for i := 0; i < 2; i++ {
+		if err := hook.Fire(infoEntry()); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if server.count() != 2 {
+		t.Errorf("Expected 2 indexed documents got %d", server.count())
+	}
+	if checks := atomic.LoadInt32(&checks); checks != 2 {
+		t.Errorf("Expected the index to be checked once more after ElasticSearch is up got %d checks", checks)
+	}
+}
@@ -0,0 +1,103 @@
+package elogrus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// recentErrorCount is the number of errors kept for DebugDump
+const recentErrorCount = 10
+
+// timedError is a failure and the time it occurred
+type timedError struct {
+	time time.Time
+	err  error
+}
+
+// Stats is a snapshot of the counters of a hook
+type Stats struct {
+	// Submitted is the number of entries fired
+	Submitted int64
+	// Sent is the number of entries indexed
+	Sent int64
+	// Retried is the number of times entries were sent again
+	Retried int64
+	// Failed is the number of entries which could not be delivered
+	Failed int64
+	// Dropped is the number of entries discarded
+	// because the queue was full
+	Dropped int64
+	// Queued is the number of entries waiting to be sent
+	Queued int
+	// LastError is the most recent delivery failure
+	LastError error
+	// LastSuccess is the time the most recent entry was indexed
+	LastSuccess time.Time
+}
+
+// Stats returns a snapshot of the counters of the hook
+func (hook *ElasticHook) Stats() Stats {
+	stats := Stats{
+		Submitted: atomic.LoadInt64(&hook.submitted),
+		Sent:      atomic.LoadInt64(&hook.sent),
+		Retried:   atomic.LoadInt64(&hook.retried),
+		Failed:    atomic.LoadInt64(&hook.failures),
+		Dropped:   atomic.LoadInt64(&hook.dropped),
+	}
+	stats.Queued = hook.QueueDepth()
+
+	hook.statsMu.Lock()
+	stats.LastError = hook.lastError
+	stats.LastSuccess = hook.lastSuccess
+	hook.statsMu.Unlock()
+	return stats
+}
+
+// QueueDepth returns the number of entries waiting to be sent
+func (hook *ElasticHook) QueueDepth() int {
+	if hook.queue == nil {
+		return 0
+	}
+	return hook.queue.len()
+}
+
+// InFlight returns the number of requests
+// currently sent to ElasticSearch
+func (hook *ElasticHook) InFlight() int {
+	return int(atomic.LoadInt64(&hook.inFlight))
+}
+
+// OldestQueuedAge returns the time the oldest queued entry is
+// waiting to be sent or zero if the queue is empty
+func (hook *ElasticHook) OldestQueuedAge() time.Duration {
+	if hook.queue == nil {
+		return 0
+	}
+	oldest := hook.queue.oldest()
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest)
+}
+
+// recordSuccess counts an indexed document
+func (hook *ElasticHook) recordSuccess() {
+	atomic.AddInt64(&hook.sent, 1)
+	atomic.StoreInt64(&hook.consecutiveFailures, 0)
+	hook.statsMu.Lock()
+	hook.lastSuccess = time.Now()
+	hook.statsMu.Unlock()
+}
+
+// recordFailure counts a document which could not be delivered
+func (hook *ElasticHook) recordFailure(err error) {
+	atomic.AddInt64(&hook.failures, 1)
+	atomic.AddInt64(&hook.consecutiveFailures, 1)
+	hook.statsMu.Lock()
+	hook.lastError = err
+	hook.recentErrors = append(hook.recentErrors, timedError{time.Now(), err})
+	if len(hook.recentErrors) > recentErrorCount {
+		hook.recentErrors = hook.recentErrors[1:]
+	}
+	hook.statsMu.Unlock()
+}
@@ -0,0 +1,42 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	hook := &ElasticHook{queue: newQueue(10, 0, DropNewest, 0)}
+	hook.queue.push(context.TODO(), &document{})
+	hook.complete(&document{}, nil)
+	hook.complete(&document{}, ErrCircuitOpen)
+	hook.drop([]*document{{}}, DropReasonEvicted)
+
+	stats := hook.Stats()
+	if stats.Sent != 1 || stats.Failed != 1 || stats.Dropped != 1 || stats.Queued != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.LastError != ErrCircuitOpen {
+		t.Errorf("Expected ErrCircuitOpen as last error got %v", stats.LastError)
+	}
+	if stats.LastSuccess.IsZero() {
+		t.Error("Expected time of last success")
+	}
+}
+
+func TestGauges(t *testing.T) {
+	hook := &ElasticHook{queue: newQueue(10, 0, DropNewest, 0)}
+	if hook.QueueDepth() != 0 || hook.OldestQueuedAge() != 0 || hook.InFlight() != 0 {
+		t.Error("Expected empty gauges")
+	}
+
+	hook.queue.push(context.TODO(), &document{created: time.Now().Add(-time.Minute)})
+	hook.queue.push(context.TODO(), &document{created: time.Now(), priority: true})
+	if hook.QueueDepth() != 2 {
+		t.Errorf("Expected queue depth 2 got %d", hook.QueueDepth())
+	}
+	if age := hook.OldestQueuedAge(); age < time.Minute {
+		t.Errorf("Expected oldest entry to wait a minute got %s", age)
+	}
+}
@@ -0,0 +1,54 @@
+package elogrus
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TagsKey is the field of an entry holding tags added to the tags of
+// the hook, either a string or a slice, e.g.
+// log.WithField(elogrus.TagsKey, []string{"billing"})
+const TagsKey = "tags"
+
+// WithTags writes the array field tags to every document, holding the
+// tags given and the tags of the field TagsKey of the entry, which is
+// not written as field any more
+func WithTags(tags ...string) Option {
+	return func(hook *ElasticHook) {
+		hook.tags = append([]string{}, tags...)
+	}
+}
+
+// addTags adds the tags of the hook and the entry to the message
+func (hook *ElasticHook) addTags(msg map[string]interface{}, entry *logrus.Entry) {
+	if hook.tags == nil {
+		return
+	}
+	tags := append([]string{}, hook.tags...)
+	switch v := entry.Data[TagsKey].(type) {
+	case nil:
+	case string:
+		tags = append(tags, v)
+	case []string:
+		tags = append(tags, v...)
+	case []interface{}:
+		for _, tag := range v {
+			tags = append(tags, fmt.Sprint(tag))
+		}
+	default:
+		tags = append(tags, fmt.Sprint(v))
+	}
+	msg[TagsKey] = tags
+}
+
+// withoutTags returns the data without the field TagsKey
+func withoutTags(data logrus.Fields) logrus.Fields {
+	stripped := make(logrus.Fields, len(data))
+	for k, v := range data {
+		if k != TagsKey {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,32 @@
+package elogrus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithTags(t *testing.T) {
+	entry := &logrus.Entry{Data: logrus.Fields{TagsKey: []string{"billing"}, "name": "joe"}}
+
+	msg := createMessage(entry, &ElasticHook{}).(map[string]interface{})
+	if _, ok := msg[TagsKey]; ok {
+		t.Error("Unexpected tags without WithTags")
+	}
+
+	hook := &ElasticHook{}
+	WithTags("api", "eu")(hook)
+	msg = createMessage(entry, hook).(map[string]interface{})
+	if tags := msg[TagsKey]; !reflect.DeepEqual(tags, []string{"api", "eu", "billing"}) {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+	if data := msg["Data"].(logrus.Fields); len(data) != 1 || data["name"] != "joe" {
+		t.Errorf("Unexpected data %v", data)
+	}
+
+	msg = createMessage(&logrus.Entry{Data: logrus.Fields{TagsKey: "slow"}}, hook).(map[string]interface{})
+	if tags := msg[TagsKey]; !reflect.DeepEqual(tags, []string{"api", "eu", "slow"}) {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+}
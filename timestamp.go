@@ -0,0 +1,101 @@
+package elogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// indexedAtField holds the time a document was sent
+const indexedAtField = "indexed_at"
+
+const (
+	// TimestampEpochMillis formats times as milliseconds since the epoch
+	TimestampEpochMillis = "epoch_millis"
+	// TimestampEpochSeconds formats times as seconds since the epoch
+	TimestampEpochSeconds = "epoch_second"
+)
+
+// WithTimestampFormat sets how the times of the documents are formatted,
+// either TimestampEpochMillis, TimestampEpochSeconds or a layout as
+// used by time.Format. Times are formatted using time.RFC3339Nano by
+// default.
+func WithTimestampFormat(format string) Option {
+	return func(hook *ElasticHook) {
+		hook.timestampFormat = format
+	}
+}
+
+// WithTimezone sets the location the times of the
+// documents are written in instead of UTC
+func WithTimezone(loc *time.Location) Option {
+	return func(hook *ElasticHook) {
+		hook.timezone = loc
+	}
+}
+
+// formatTime returns the time as written to the documents
+func (hook *ElasticHook) formatTime(t time.Time) interface{} {
+	return hook.formatTimeAs(t, hook.timestampFormat)
+}
+
+// formatTimeAs returns the time written using the format. An
+// empty format uses the format of the documents.
+func (hook *ElasticHook) formatTimeAs(t time.Time, format string) interface{} {
+	if format == "" {
+		format = hook.timestampFormat
+	}
+	if hook.timezone != nil {
+		t = t.In(hook.timezone)
+	} else {
+		t = t.UTC()
+	}
+	switch format {
+	case "":
+		return t.Format(time.RFC3339Nano)
+	case TimestampEpochMillis:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimestampEpochSeconds:
+		return t.Unix()
+	default:
+		return t.Format(format)
+	}
+}
+
+// WithIndexedAt adds the field indexed_at holding the time an entry
+// was sent to every document, so the delay between firing and sending
+// can be compared to @timestamp
+func WithIndexedAt() Option {
+	return func(hook *ElasticHook) {
+		hook.indexedAt = true
+	}
+}
+
+// payload returns the body of the document as sent to ElasticSearch
+func (hook *ElasticHook) payload(doc *document) json.RawMessage {
+	if !hook.indexedAt {
+		return doc.body
+	}
+	return addField(doc.body, indexedAtField, hook.formatTime(time.Now()))
+}
+
+// addField adds the field to the JSON object. Bodies
+// which are not objects are returned unchanged.
+func addField(body json.RawMessage, name string, value interface{}) json.RawMessage {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) < 2 || trimmed[0] != '{' {
+		return body
+	}
+	field, err := json.Marshal(map[string]interface{}{name: value})
+	if err != nil {
+		return body
+	}
+
+	rest := bytes.TrimSpace(trimmed[1:])
+	out := make([]byte, 0, len(field)+len(body)+1)
+	out = append(out, field[:len(field)-1]...)
+	if rest[0] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, rest...)
+}
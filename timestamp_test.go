@@ -0,0 +1,46 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAddField(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{"Message":"Hello"}`: `{"indexed_at":"now","Message":"Hello"}`,
+		`{}`:                  `{"indexed_at":"now"}`,
+		` { } `:               `{"indexed_at":"now"}`,
+		`[]`:                  `[]`,
+	} {
+		if got := string(addField(json.RawMessage(body), indexedAtField, "now")); got != expected {
+			t.Errorf("Expected %s for %s got %s", expected, body, got)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 6000000, time.FixedZone("CET", 3600))
+	for format, expected := range map[string]interface{}{
+		"":                    "2018-01-02T02:04:05.006Z",
+		TimestampEpochMillis:  int64(1514858645006),
+		TimestampEpochSeconds: int64(1514858645),
+		"2006-01-02 15:04:05": "2018-01-02 02:04:05",
+	} {
+		hook := &ElasticHook{}
+		WithTimestampFormat(format)(hook)
+		if got := hook.formatTime(ts); got != expected {
+			t.Errorf("Expected %v for format %q got %v", expected, format, got)
+		}
+	}
+}
+
+func TestWithTimezone(t *testing.T) {
+	hook := &ElasticHook{}
+	WithTimezone(time.FixedZone("CET", 3600))(hook)
+
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	if got := hook.formatTime(ts); got != "2018-01-02T04:04:05+01:00" {
+		t.Errorf("Unexpected time %v", got)
+	}
+}
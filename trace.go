@@ -0,0 +1,33 @@
+package elogrus
+
+import (
+	"context"
+)
+
+// Tracer starts spans around the operations of a hook, e.g. to show
+// them in distributed traces. The elogrusotel package provides a
+// Tracer using OpenTelemetry.
+type Tracer interface {
+	// Start starts a span named after the operation and returns
+	// a function ending it with the outcome of the operation
+	Start(ctx context.Context, operation string, attributes map[string]interface{}) (end func(err error))
+}
+
+// WithTracer makes the hook create spans for fires,
+// bulk requests and retries using the tracer
+func WithTracer(tracer Tracer) Option {
+	return func(hook *ElasticHook) {
+		hook.tracer = tracer
+	}
+}
+
+// startSpan starts a span if a tracer is set
+func (hook *ElasticHook) startSpan(ctx context.Context, operation string, attributes map[string]interface{}) func(err error) {
+	if hook.tracer == nil {
+		return func(err error) {}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return hook.tracer.Start(ctx, operation, attributes)
+}
@@ -0,0 +1,34 @@
+package elogrus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// spanRecorder is a Tracer remembering the started operations
+type spanRecorder struct {
+	operations []string
+}
+
+func (r *spanRecorder) Start(ctx context.Context, operation string, attributes map[string]interface{}) func(err error) {
+	r.operations = append(r.operations, operation)
+	return func(err error) {}
+}
+
+func TestTracer(t *testing.T) {
+	r := new(spanRecorder)
+	hook := &ElasticHook{
+		index:    func() string { return "log" },
+		fireFunc: func(entry *logrus.Entry, hook *ElasticHook, indexName string) error { return nil },
+	}
+	WithTracer(r)(hook)
+
+	if err := hook.Fire(logrus.NewEntry(logrus.New())); err != nil {
+		t.Fatalf("Unexpected fire error: %s", err)
+	}
+	if len(r.operations) != 1 || r.operations[0] != "elogrus.fire" {
+		t.Errorf("Expected fire span got %v", r.operations)
+	}
+}
@@ -0,0 +1,64 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// maxSafeDepth limits the nesting of maps and slices
+// walked looking for unserializable values
+const maxSafeDepth = 32
+
+// encodeSafe serializes the message which failed with err again with
+// the values which cannot be serialized, like channels, functions or
+// cyclic structures, replaced by a placeholder describing them. Other
+// errors and messages which are no objects are returned unchanged.
+func (hook *ElasticHook) encodeSafe(msg interface{}, err error) ([]byte, error) {
+	var typeErr *json.UnsupportedTypeError
+	var valueErr *json.UnsupportedValueError
+	if !errors.As(err, &typeErr) && !errors.As(err, &valueErr) {
+		return nil, err
+	}
+	safe, ok := safeValue(msg, maxSafeDepth).(map[string]interface{})
+	if !ok {
+		return nil, err
+	}
+	return hook.encode(safe)
+}
+
+// safeValue returns the value if it can be serialized. Maps and slices
+// which cannot be serialized are copied with their unserializable
+// values replaced, errors are replaced by their message and other
+// values by a placeholder.
+func safeValue(value interface{}, depth int) interface{} {
+	_, err := json.Marshal(value)
+	if err == nil {
+		return value
+	}
+	if e, ok := value.(error); ok {
+		return e.Error()
+	}
+
+	rv := reflect.ValueOf(value)
+	if _, ok := value.(json.Marshaler); !ok && depth > 0 {
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				safe := make(map[string]interface{}, rv.Len())
+				for iter := rv.MapRange(); iter.Next(); {
+					safe[iter.Key().String()] = safeValue(iter.Value().Interface(), depth-1)
+				}
+				return safe
+			}
+		case reflect.Slice, reflect.Array:
+			safe := make([]interface{}, rv.Len())
+			for i := range safe {
+				safe[i] = safeValue(rv.Index(i).Interface(), depth-1)
+			}
+			return safe
+		}
+	}
+	return fmt.Sprintf("[unserializable %T: %v]", value, err)
+}
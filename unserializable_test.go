@@ -0,0 +1,81 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type cyclicNode struct {
+	Next *cyclicNode
+}
+
+func TestUnserializableValues(t *testing.T) {
+	node := &cyclicNode{}
+	node.Next = node
+	entry := &logrus.Entry{Message: "Hello", Data: logrus.Fields{
+		"ok":     1,
+		"chan":   make(chan int),
+		"func":   func() {},
+		"cycle":  node,
+		"nan":    math.NaN(),
+		"nested": map[string]interface{}{"ok": "yes", "chan": make(chan int)},
+	}}
+	doc, err := (&ElasticHook{}).newDocument(entry, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg struct {
+		Message string
+		Data    map[string]interface{}
+	}
+	if err := json.Unmarshal(doc.body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Message != "Hello" || msg.Data["ok"] != 1.0 {
+		t.Errorf("Expected serializable values to be kept got %s", doc.body)
+	}
+	for _, k := range []string{"chan", "func", "cycle", "nan"} {
+		if s, _ := msg.Data[k].(string); !strings.HasPrefix(s, "[unserializable ") {
+			t.Errorf("Expected placeholder for %s got %v", k, msg.Data[k])
+		}
+	}
+	nested, _ := msg.Data["nested"].(map[string]interface{})
+	if s, _ := nested["chan"].(string); nested["ok"] != "yes" || !strings.HasPrefix(s, "[unserializable chan int") {
+		t.Errorf("Expected nested placeholder got %v", msg.Data["nested"])
+	}
+	if _, ok := entry.Data["chan"].(chan int); !ok {
+		t.Error("Expected the data of the entry to be kept")
+	}
+}
+
+func TestUnserializableCreatorCalledOnce(t *testing.T) {
+	calls := 0
+	hook := &ElasticHook{}
+	WithMessageCreatorV2(func(entry *logrus.Entry, hook *ElasticHook) (interface{}, error) {
+		calls++
+		return map[string]interface{}{"Message": entry.Message, "chan": make(chan int)}, nil
+	})(hook)
+	doc, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || !strings.Contains(string(doc.body), `"chan":"[unserializable chan int`) {
+		t.Errorf("Expected the created message to be sanitized after %d call got %s", calls, doc.body)
+	}
+}
+
+func TestUnserializableInvalidDocument(t *testing.T) {
+	calls := 0
+	hook := &ElasticHook{}
+	WithMessageCreatorV2(func(entry *logrus.Entry, hook *ElasticHook) (interface{}, error) {
+		calls++
+		return json.RawMessage(`{"Message":`), nil
+	})(hook)
+	if _, err := hook.newDocument(&logrus.Entry{Message: "Hello"}, "index"); err == nil || calls != 1 {
+		t.Errorf("Expected invalid documents to fail after 1 call got %v after %d", err, calls)
+	}
+}
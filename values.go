@@ -0,0 +1,131 @@
+package elogrus
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithTimeValues writes time.Time values stored anywhere in the fields
+// of the entries using the format, which is one of the formats accepted
+// by WithTimestampFormat. An empty format uses the format of @timestamp.
+func WithTimeValues(format string) Option {
+	return func(hook *ElasticHook) {
+		hook.timeValues = true
+		hook.valueTimeFormat = format
+	}
+}
+
+// WithStringerValues writes values implementing fmt.Stringer stored
+// anywhere in the fields of the entries as the string returned by their
+// String method. Values implementing json.Marshaler are kept.
+func WithStringerValues() Option {
+	return func(hook *ElasticHook) {
+		hook.stringerValues = true
+	}
+}
+
+// WithErrorValues writes errors stored anywhere in the fields of the
+// entries like the error stored in the field logrus.ErrorKey instead
+// of the fields of the error struct
+func WithErrorValues() Option {
+	return func(hook *ElasticHook) {
+		hook.errorValues = true
+	}
+}
+
+// convertsValues reports whether field values are converted
+func (hook *ElasticHook) convertsValues() bool {
+	return hook.timeValues || hook.stringerValues || hook.errorValues
+}
+
+// convertFields returns the fields with time, stringer and error values
+// converted as configured
+func (hook *ElasticHook) convertFields(data logrus.Fields) logrus.Fields {
+	converted := make(logrus.Fields, len(data))
+	for k, v := range data {
+		converted[k] = hook.convertValue(v)
+	}
+	return converted
+}
+
+func (hook *ElasticHook) convertValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		if hook.timeValues {
+			return hook.formatTimeAs(v, hook.valueTimeFormat)
+		}
+		return v
+	case *time.Time:
+		if hook.timeValues && v != nil {
+			return hook.formatTimeAs(*v, hook.valueTimeFormat)
+		}
+		return v
+	case error:
+		if hook.errorValues && !isNilPointer(v) {
+			return hook.errorValue(v)
+		}
+		return v
+	case json.Marshaler:
+		return v
+	case fmt.Stringer:
+		if hook.stringerValues && !isNilPointer(v) {
+			return v.String()
+		}
+		return v
+	case map[string]interface{}:
+		return map[string]interface{}(hook.convertFields(v))
+	case logrus.Fields:
+		return hook.convertFields(v)
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = hook.convertValue(item)
+		}
+		return converted
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String || !mayConvert(rv.Type().Elem()) {
+			return value
+		}
+		converted := make(map[string]interface{}, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			converted[iter.Key().String()] = hook.convertValue(iter.Value().Interface())
+		}
+		return converted
+	case reflect.Slice, reflect.Array:
+		if !mayConvert(rv.Type().Elem()) {
+			return value
+		}
+		converted := make([]interface{}, rv.Len())
+		for i := range converted {
+			converted[i] = hook.convertValue(rv.Index(i).Interface())
+		}
+		return converted
+	}
+	return value
+}
+
+// mayConvert reports whether values of the type may hold
+// values converted by convertValue
+func mayConvert(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Struct, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// isNilPointer reports whether the value is a nil pointer
+func isNilPointer(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,76 @@
+package elogrus
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stringerStruct struct {
+	Name string
+}
+
+func (s stringerStruct) String() string {
+	return "stringer " + s.Name
+}
+
+func TestConvertValues(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	hook := &ElasticHook{}
+	WithTimeValues(TimestampEpochSeconds)(hook)
+	WithStringerValues()(hook)
+	WithErrorValues()(hook)
+
+	var nilTime *time.Time
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{
+		"time":     ts,
+		"nilTime":  nilTime,
+		"stringer": stringerStruct{"a"},
+		"ip":       net.IPv4(127, 0, 0, 1),
+		"nested": map[string]interface{}{
+			"err":   fmt.Errorf("Failed"),
+			"times": []time.Time{ts},
+		},
+		"list": []interface{}{stringerStruct{"b"}, 1},
+		"ints": []int{1, 2},
+	}})
+	expected := logrus.Fields{
+		"time":     ts.Unix(),
+		"nilTime":  nilTime,
+		"stringer": "stringer a",
+		"ip":       "127.0.0.1",
+		"nested": map[string]interface{}{
+			"err":   "Failed",
+			"times": []interface{}{ts.Unix()},
+		},
+		"list": []interface{}{"stringer b", 1},
+		"ints": []int{1, 2},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %#v got %#v", expected, data)
+	}
+}
+
+func TestConvertValuesDisabled(t *testing.T) {
+	hook := &ElasticHook{}
+	WithTimeValues("")(hook)
+	WithTimestampFormat(time.RFC1123)(hook)
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := fmt.Errorf("Failed")
+	data := hook.entryData(&logrus.Entry{Data: logrus.Fields{
+		"time":     ts,
+		"stringer": stringerStruct{"a"},
+		"err":      err,
+	}})
+	if data["time"] != ts.Format(time.RFC1123) {
+		t.Errorf("Expected time in the timestamp format got %v", data["time"])
+	}
+	if data["stringer"] != (stringerStruct{"a"}) || data["err"] != err {
+		t.Errorf("Expected other values to be kept got %v", data)
+	}
+}
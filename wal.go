@@ -0,0 +1,339 @@
+package elogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	walExtension   = ".wal"
+	walSegmentSize = 16 << 20
+	// walRetryDelay is the time to wait after
+	// reading the write-ahead log failed
+	walRetryDelay = time.Second
+)
+
+// WithWAL makes an asynchronous hook write every entry to a write-ahead
+// log in dir before it is queued for sending. Entries which were not
+// delivered when the process stopped are sent after the next start.
+// The log is only truncated once all entries of a segment are
+// completed, so entries may be delivered more than once after a crash.
+// Handlers are called with a nil entry for documents read from the log.
+func WithWAL(dir string) Option {
+	return func(hook *ElasticHook) {
+		hook.walDir = dir
+	}
+}
+
+// walRecord is a single line of a write-ahead log segment
+type walRecord struct {
+	Index    string          `json:"index"`
+	Priority bool            `json:"priority,omitempty"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// walSegment is a single file of the write-ahead log
+type walSegment struct {
+	id   uint64
+	path string
+	// size is the number of bytes written to the segment
+	size int64
+	// read is the number of bytes handed over to the queue
+	read int64
+	// pending is the number of queued documents not completed yet
+	pending int
+	sealed  bool
+}
+
+// wal is a write-ahead log made of segment files which
+// are removed as soon as all their documents are completed
+type wal struct {
+	dir string
+	mu  sync.Mutex
+	// segments are ordered by age, the last one is written to
+	segments []*walSegment
+	file     *os.File
+	// written is signalled whenever a document was appended
+	written chan struct{}
+	// replayed is the number of documents left by the previous run
+	replayed int
+}
+
+func openWAL(dir string) (*wal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*"+walExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wal{
+		dir:     dir,
+		written: make(chan struct{}, 1),
+	}
+	var next uint64
+	for _, name := range names {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), walExtension), 16, 64)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		seg := &walSegment{
+			id:     id,
+			path:   name,
+			size:   info.Size(),
+			sealed: true,
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		docs, _ := walRecords(data, seg)
+		w.replayed += len(docs)
+		w.segments = append(w.segments, seg)
+		if id >= next {
+			next = id + 1
+		}
+	}
+	sort.Slice(w.segments, func(i, j int) bool {
+		return w.segments[i].id < w.segments[j].id
+	})
+
+	if err := w.rotate(next); err != nil {
+		return nil, err
+	}
+	w.cleanup()
+	return w, nil
+}
+
+// rotate seals the active segment and starts a
+// new one. Must be called with w.mu held.
+func (w *wal) rotate(id uint64) error {
+	path := filepath.Join(w.dir, fmt.Sprintf("%016x%s", id, walExtension))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.segments[len(w.segments)-1].sealed = true
+	}
+	w.file = file
+	w.segments = append(w.segments, &walSegment{id: id, path: path})
+	return nil
+}
+
+// append writes the document to the active segment
+func (w *wal) append(doc *document) error {
+	line, err := json.Marshal(walRecord{
+		Index:    doc.index,
+		Priority: doc.priority,
+		Body:     doc.body,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	if w.file == nil {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	active := w.segments[len(w.segments)-1]
+	if active.size > 0 && active.size+int64(len(line)) > walSegmentSize {
+		if err := w.rotate(active.id + 1); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+		active = w.segments[len(w.segments)-1]
+	}
+	n, err := w.file.Write(line)
+	active.size += int64(n)
+	w.mu.Unlock()
+
+	select {
+	case w.written <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// next reads the documents not handed over to the queue yet
+// from the oldest segment containing any
+func (w *wal) next() ([]*document, error) {
+	w.mu.Lock()
+	var seg *walSegment
+	for _, s := range w.segments {
+		if s.read < s.size {
+			seg = s
+			break
+		}
+	}
+	if seg == nil {
+		w.mu.Unlock()
+		return nil, nil
+	}
+	offset, size, sealed := seg.read, seg.size, seg.sealed
+	w.mu.Unlock()
+
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size-offset)
+	_, err = file.ReadAt(data, offset)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	docs, consumed := walRecords(data, seg)
+	// Skip an incomplete last line left behind by a crash
+	if sealed {
+		consumed = len(data)
+	}
+
+	w.mu.Lock()
+	seg.read = offset + int64(consumed)
+	seg.pending += len(docs)
+	w.cleanup()
+	w.mu.Unlock()
+	return docs, nil
+}
+
+// walRecords returns the documents of the complete lines of the data
+// read from the segment and the number of bytes these lines take
+func walRecords(data []byte, seg *walSegment) ([]*document, int) {
+	var docs []*document
+	consumed := 0
+	for {
+		end := bytes.IndexByte(data[consumed:], '\n')
+		if end < 0 {
+			break
+		}
+		var record walRecord
+		if err := json.Unmarshal(data[consumed:consumed+end], &record); err == nil {
+			docs = append(docs, &document{
+				index:    record.Index,
+				body:     record.Body,
+				priority: record.Priority,
+				segment:  seg,
+				created:  time.Now(),
+			})
+		}
+		consumed += end + 1
+	}
+	return docs, consumed
+}
+
+// ack marks a document of the segment as completed
+func (w *wal) ack(seg *walSegment) {
+	w.mu.Lock()
+	seg.pending--
+	w.cleanup()
+	w.mu.Unlock()
+}
+
+// cleanup removes all segments whose documents are completed and
+// starts a new segment once the active one is completed. Must be
+// called with w.mu held.
+func (w *wal) cleanup() {
+	if active := w.segments[len(w.segments)-1]; w.file != nil && active.size > 0 && active.done() {
+		w.rotate(active.id + 1)
+	}
+
+	segments := w.segments[:0]
+	for _, seg := range w.segments {
+		if seg.sealed && seg.done() {
+			os.Remove(seg.path)
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	w.segments = segments
+}
+
+// done reports whether all documents of the segment are completed
+func (seg *walSegment) done() bool {
+	return seg.read == seg.size && seg.pending == 0
+}
+
+func (w *wal) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+}
+
+// runWAL hands the documents written to the
+// write-ahead log over to the queue
+func (hook *ElasticHook) runWAL() {
+	defer hook.wg.Done()
+	defer hook.wal.close()
+
+	for {
+		docs, err := hook.wal.next()
+		if err != nil {
+			hook.reportError(err, nil)
+			if !hook.wait(walRetryDelay) {
+				return
+			}
+			continue
+		}
+		if len(docs) == 0 {
+			select {
+			case <-hook.wal.written:
+			case <-hook.closing:
+				hook.keepWAL(nil)
+				return
+			case <-hook.ctx.Done():
+				hook.keepWAL(nil)
+				return
+			}
+			continue
+		}
+		for i, doc := range docs {
+			select {
+			case <-hook.closing:
+				hook.keepWAL(docs[i:])
+				return
+			default:
+			}
+			if err := hook.queue.pushWait(hook.ctx, doc); err != nil {
+				hook.keepWAL(docs[i:])
+				return
+			}
+		}
+	}
+}
+
+// keepWAL counts the documents read but not queued and those not read
+// yet when the hook is closed. They stay in the log for the next start.
+func (hook *ElasticHook) keepWAL(docs []*document) {
+	kept := len(docs)
+	for {
+		more, err := hook.wal.next()
+		if err != nil || len(more) == 0 {
+			break
+		}
+		kept += len(more)
+	}
+	atomic.AddInt64(&hook.kept, int64(kept))
+}
@@ -0,0 +1,175 @@
+package elogrus
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWALReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := openWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []string{"a", "b"} {
+		if err := w.append(&document{index: index, body: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	docs, err := w.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents got %d", len(docs))
+	}
+	// Only the first document is delivered before the restart
+	w.ack(docs[0].segment)
+	w.close()
+
+	w, err = openWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	docs, err = w.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[1].index != "b" {
+		t.Fatalf("Expected undelivered segment to be replayed got %d documents", len(docs))
+	}
+}
+
+func TestWALCleanup(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := openWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	if err := w.append(&document{index: "a", body: []byte(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := w.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.ack(docs[0].segment)
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*"+walExtension))
+	if len(names) != 1 {
+		t.Errorf("Expected only the new active segment to be left got %v", names)
+	}
+	if docs, _ := w.next(); len(docs) != 0 {
+		t.Errorf("Expected no documents got %d", len(docs))
+	}
+}
+
+func TestWALFlushAfterReplay(t *testing.T) {
+	dir := t.TempDir()
+	w, err := openWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := w.append(&document{index: "log", body: []byte(`{"Message":"old"}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.close()
+
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+			if strings.Contains(string(body), "new") {
+				// The new entry is delivered after the replayed ones
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+		return false
+	})
+	defer server.Close()
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log", WithWAL(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.InfoLevel
+	entry.Message = "new"
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 6 {
+		t.Errorf("Expected 6 indexed documents after the flush got %d", server.count())
+	}
+	if err := hook.Close(context.TODO()); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	names, _ := filepath.Glob(filepath.Join(dir, "*"+walExtension))
+	if len(names) != 1 {
+		t.Errorf("Expected only the new active segment to be left got %v", names)
+	}
+}
+
+func TestWALCloseDeadline(t *testing.T) {
+	dir := t.TempDir()
+	release := make(chan struct{})
+	server, client := newStubElastic(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == "POST" {
+			// Never answer before the request is cancelled
+			ioutil.ReadAll(r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	defer server.Close()
+	defer close(release)
+	hook, err := NewAsyncElasticHook(client, "localhost", logrus.DebugLevel, "log", WithWAL(dir),
+		WithWorkers(1), WithQueueSize(1), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries are in flight, queued or still in the log when closing
+	for i := 0; i < 4; i++ {
+		if err := hook.Fire(infoEntry()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = hook.Close(ctx)
+	if e, ok := err.(*UndeliveredError); !ok || e.Count != 4 || !errors.Is(err, ErrUndelivered) {
+		t.Errorf("Expected 4 undelivered entries got %v", err)
+	}
+
+	w, err := openWAL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+	if w.replayed != 4 {
+		t.Errorf("Expected the 4 entries to be kept in the log got %d", w.replayed)
+	}
+}